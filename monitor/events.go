@@ -49,9 +49,11 @@ func (m *monitor) manageEvents(ctx context.Context) {
 				&sdk.EventsSelector{
 					Source: "brigade.sh/github",
 					// These are all the phases where something worth reporting might have
-					// occurred. Basically, it just excludes pending and canceled phases.
+					// occurred. Basically, it just excludes the pending phase. Canceled
+					// events are included because they may have been superseded.
 					WorkerPhases: []sdk.WorkerPhase{
 						sdk.WorkerPhaseAborted,
+						sdk.WorkerPhaseCanceled,
 						sdk.WorkerPhaseFailed,
 						sdk.WorkerPhaseRunning,
 						sdk.WorkerPhaseStarting,
@@ -169,6 +171,10 @@ func (m *monitor) monitorEventInternal(
 			)
 		}
 
+		// If the receiver canceled this Event because a newer one superseded it,
+		// this is the SHA of the commit the newer Event pertains to.
+		supersededBy := getSupersededBy(event)
+
 		// Loop through all of the Event's Jobs and report status for each
 		var allJobsCompleted = true
 		for _, job := range event.Worker.Jobs {
@@ -182,6 +188,15 @@ func (m *monitor) monitorEventInternal(
 				job.Status.Phase,
 				job.Spec.Fallible,
 			)
+			jobCompleted := job.Status.Phase.IsTerminal()
+			// A superseded Event's Worker may have been aborted before all of its
+			// Jobs finished. Those Jobs never will, so we report them as canceled.
+			if supersededBy != "" && !jobCompleted &&
+				event.Worker.Status.Phase.IsTerminal() {
+				status = statusCompleted
+				conclusion = conclusionCanceled
+				jobCompleted = true
+			}
 
 			// Note: This will return an empty string if the job isn't in a terminal
 			// phase
@@ -242,7 +257,7 @@ func (m *monitor) monitorEventInternal(
 					return errors.Wrap(err, "error updating check run; giving up")
 				}
 			}
-			if job.Status.Phase.IsTerminal() {
+			if jobCompleted {
 				// Record that we're done reporting on this particular Job so that we
 				// can skip over it next time we follow up on all of the Event's Jobs
 				reportedComplete[job.Name] = struct{}{}
@@ -314,14 +329,8 @@ func (m *monitor) createCheckRun(
 			Time: *job.Status.Ended,
 		}
 	}
-	if logs != "" {
-		summary := "Job Logs"
-		checkRunOpts.Output = &github.CheckRunOutput{
-			Title:   &checkRunName,
-			Summary: &summary,
-			Text:    &logs,
-		}
-	}
+	checkRunOpts.Output =
+		getCheckRunOutput(checkRunName, event, conclusion, logs)
 	checkRunsClient, err := m.checkRunsClientFactory.NewCheckRunsClient(
 		ctx,
 		app.AppID,
@@ -372,14 +381,8 @@ func (m *monitor) updateCheckRun(
 			Time: *job.Status.Ended,
 		}
 	}
-	if logs != "" {
-		summary := "Job Logs"
-		checkRunOpts.Output = &github.CheckRunOutput{
-			Title:   &checkRunName,
-			Summary: &summary,
-			Text:    &logs,
-		}
-	}
+	checkRunOpts.Output =
+		getCheckRunOutput(checkRunName, event, conclusion, logs)
 	checkRunsClient, err := m.checkRunsClientFactory.NewCheckRunsClient(
 		ctx,
 		app.AppID,
@@ -404,6 +407,44 @@ func (m *monitor) updateCheckRun(
 	)
 }
 
+// getCheckRunOutput returns output for the check run having the specified name,
+// reporting on a job belonging to the specified event and having the specified
+// conclusion and logs. Jobs that were canceled because their event was
+// superseded are summarized as such. If there is nothing to report, nil is
+// returned.
+func getCheckRunOutput(
+	checkRunName string,
+	event sdk.Event,
+	conclusion string,
+	logs string,
+) *github.CheckRunOutput {
+	summary := "Job Logs"
+	if supersededBy := getSupersededBy(event); supersededBy != "" &&
+		conclusion == conclusionCanceled {
+		summary = fmt.Sprintf("Superseded by %s", supersededBy)
+	} else if logs == "" {
+		return nil
+	}
+	output := &github.CheckRunOutput{
+		Title:   &checkRunName,
+		Summary: &summary,
+	}
+	if logs != "" {
+		output.Text = &logs
+	}
+	return output
+}
+
+// getSupersededBy returns the SHA of the commit pertaining to the event that
+// superseded the specified event. If the specified event was not superseded,
+// an empty string is returned.
+func getSupersededBy(event sdk.Event) string {
+	if event.SourceState == nil {
+		return ""
+	}
+	return event.SourceState.State["supersededBy"]
+}
+
 func (m *monitor) checkRunStatusAndConclusionFromJobStatus(
 	jobPhase sdk.JobPhase,
 	fallible bool,
@@ -405,6 +405,86 @@ func TestMonitorEventInternal(t *testing.T) {
 				require.Contains(t, err.Error(), "error updating check run; giving up")
 			},
 		},
+		{
+			name: "superseded event",
+			monitor: &monitor{
+				config: testConfig,
+				eventsClient: &sdkTesting.MockEventsClient{
+					GetFn: func(
+						context.Context,
+						string,
+						*sdk.EventGetOptions,
+					) (sdk.Event, error) {
+						return sdk.Event{
+							Labels: map[string]string{
+								"appID": "86",
+							},
+							SourceState: &sdk.SourceState{
+								State: map[string]string{
+									"installationID": "42",
+									"supersededBy":   "1234567",
+								},
+							},
+							Worker: &sdk.Worker{
+								Status: sdk.WorkerStatus{
+									Phase: sdk.WorkerPhaseAborted,
+								},
+								Jobs: []sdk.Job{
+									{
+										Name: "italian",
+										Status: &sdk.JobStatus{
+											Phase: sdk.JobPhaseRunning,
+										},
+									},
+								},
+							},
+						}, nil
+					},
+					UpdateSourceStateFn: func(
+						context.Context,
+						string,
+						sdk.SourceState,
+						*sdk.EventSourceStateUpdateOptions,
+					) error {
+						return nil
+					},
+				},
+				getJobLogsFn: func(context.Context, string, sdk.Job) (string, error) {
+					return "", nil
+				},
+				checkRunsClientFactory: &ghlib.MockCheckRunsClientFactory{
+					NewCheckRunsClientFn: func(
+						context.Context,
+						int64,
+						int64,
+						[]byte,
+					) (ghlib.CheckRunsClient, error) {
+						return &ghlib.MockCheckRunsClient{
+							CreateCheckRunFn: func(
+								_ context.Context,
+								_ string,
+								_ string,
+								opts github.CreateCheckRunOptions,
+							) (*github.CheckRun, *github.Response, error) {
+								require.Equal(t, statusCompleted, *opts.Status)
+								require.Equal(t, conclusionCanceled, *opts.Conclusion)
+								require.Equal(
+									t,
+									"Superseded by 1234567",
+									*opts.Output.Summary,
+								)
+								return &github.CheckRun{
+									ID: &testCheckRunID,
+								}, nil, nil
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -709,6 +789,79 @@ func TestUpdateCheckRun(t *testing.T) {
 	}
 }
 
+func TestGetCheckRunOutput(t *testing.T) {
+	const testCheckRunName = "italian:pizza"
+	const testLogs = "some logs"
+	testSupersededEvent := sdk.Event{
+		SourceState: &sdk.SourceState{
+			State: map[string]string{
+				"supersededBy": "1234567",
+			},
+		},
+	}
+	testCases := []struct {
+		name            string
+		event           sdk.Event
+		conclusion      string
+		logs            string
+		expectedSummary string
+		expectedText    string
+	}{
+		{
+			name:       "no logs",
+			event:      sdk.Event{},
+			conclusion: conclusionSuccess,
+		},
+		{
+			name:            "logs",
+			event:           sdk.Event{},
+			conclusion:      conclusionSuccess,
+			logs:            testLogs,
+			expectedSummary: "Job Logs",
+			expectedText:    testLogs,
+		},
+		{
+			name:            "superseded event; job canceled without logs",
+			event:           testSupersededEvent,
+			conclusion:      conclusionCanceled,
+			expectedSummary: "Superseded by 1234567",
+		},
+		{
+			name:            "superseded event; job canceled with logs",
+			event:           testSupersededEvent,
+			conclusion:      conclusionCanceled,
+			logs:            testLogs,
+			expectedSummary: "Superseded by 1234567",
+			expectedText:    testLogs,
+		},
+		{
+			name:            "superseded event; job succeeded",
+			event:           testSupersededEvent,
+			conclusion:      conclusionSuccess,
+			logs:            testLogs,
+			expectedSummary: "Job Logs",
+			expectedText:    testLogs,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			output := getCheckRunOutput(
+				testCheckRunName,
+				testCase.event,
+				testCase.conclusion,
+				testCase.logs,
+			)
+			if testCase.expectedSummary == "" {
+				require.Nil(t, output)
+				return
+			}
+			require.Equal(t, testCheckRunName, output.GetTitle())
+			require.Equal(t, testCase.expectedSummary, output.GetSummary())
+			require.Equal(t, testCase.expectedText, output.GetText())
+		})
+	}
+}
+
 func TestCheckRunStatusAndConclusionFromJobStatus(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -0,0 +1,92 @@
+package payloads
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// filesystemStore is an implementation of the Store interface that stores
+// payloads as files beneath a root directory. URLs returned by this
+// implementation use the file scheme.
+type filesystemStore struct {
+	rootDir string
+}
+
+// NewFilesystemStore returns an implementation of the Store interface that
+// stores payloads as files beneath the specified root directory. For payloads
+// to be retrievable by other processes, such as Brigade workers, the directory
+// must be on a volume that is shared with them.
+func NewFilesystemStore(rootDir string) (Store, error) {
+	if rootDir == "" {
+		return nil, errors.New("filesystem payload store has no root directory")
+	}
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving path %s", rootDir)
+	}
+	return &filesystemStore{
+		rootDir: absRootDir,
+	}, nil
+}
+
+func (f *filesystemStore) Put(
+	_ context.Context,
+	key string,
+	payload []byte,
+) (string, error) {
+	path, err := f.getPath(key)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", errors.Wrapf(err, "error creating directory for %s", path)
+	}
+	if err = ioutil.WriteFile(path, payload, 0644); err != nil {
+		return "", errors.Wrapf(err, "error writing %s", path)
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String(), nil
+}
+
+func (f *filesystemStore) Get(
+	_ context.Context,
+	rawURL string,
+) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing URL %s", rawURL)
+	}
+	if u.Scheme != "file" {
+		return nil, errors.Errorf("URL %s does not use the file scheme", rawURL)
+	}
+	// Payload references are found in event payloads, so we refuse to read
+	// anything that isn't beneath the root directory.
+	rel, err := filepath.Rel(f.rootDir, filepath.Clean(u.Path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, errors.Errorf(
+			"URL %s does not reference a path beneath %s",
+			rawURL,
+			f.rootDir,
+		)
+	}
+	payload, err := ioutil.ReadFile(u.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", u.Path)
+	}
+	return payload, nil
+}
+
+// getPath returns the path of the file in which the payload having the
+// specified key is stored.
+func (f *filesystemStore) getPath(key string) (string, error) {
+	path := filepath.Join(f.rootDir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, f.rootDir+string(filepath.Separator)) {
+		return "", errors.Errorf("invalid payload key %q", key)
+	}
+	return path, nil
+}
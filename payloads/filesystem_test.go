@@ -0,0 +1,55 @@
+package payloads
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFilesystemStore(t *testing.T) {
+	_, err := NewFilesystemStore("")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "has no root directory")
+	rootDir := t.TempDir()
+	store, err := NewFilesystemStore(rootDir)
+	require.NoError(t, err)
+	require.IsType(t, &filesystemStore{}, store)
+	require.Equal(t, rootDir, store.(*filesystemStore).rootDir)
+}
+
+func TestFilesystemStorePutAndGet(t *testing.T) {
+	rootDir := t.TempDir()
+	store, err := NewFilesystemStore(rootDir)
+	require.NoError(t, err)
+	testPayload := []byte(`{"foo":"bar"}`)
+
+	_, err = store.Put(context.Background(), "../foo.json", testPayload)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid payload key")
+
+	url, err := store.Put(context.Background(), "foo.json", testPayload)
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.Join(rootDir, "foo.json"), url)
+
+	payload, err := store.Get(context.Background(), url)
+	require.NoError(t, err)
+	require.Equal(t, testPayload, payload)
+
+	_, err = store.Get(context.Background(), "s3://foo/foo.json")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not use the file scheme")
+
+	_, err = store.Get(context.Background(), "file:///etc/passwd")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not reference a path beneath")
+
+	_, err = store.Get(context.Background(), url+"/../../foo.json")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not reference a path beneath")
+
+	_, err = store.Get(context.Background(), "file://"+rootDir+"/bar.json")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error reading")
+}
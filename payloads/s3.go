@@ -0,0 +1,228 @@
+package payloads
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// s3DateFormat is the format of timestamps used in AWS Signature Version 4.
+	s3DateFormat = "20060102T150405Z"
+	// s3ScopeDateFormat is the format of dates used in the credential scope of
+	// AWS Signature Version 4.
+	s3ScopeDateFormat = "20060102"
+)
+
+// S3Config encapsulates configuration options for an S3-compatible payload
+// store.
+type S3Config struct {
+	// Endpoint is the address of the S3-compatible API, including leading
+	// protocol (http:// or https://). e.g. https://s3.us-east-1.amazonaws.com or
+	// http://minio.minio.svc.cluster.local:9000
+	Endpoint string
+	// Bucket is the name of the bucket in which payloads are stored. It must
+	// already exist.
+	Bucket string
+	// Region is the region in which the bucket exists. MinIO ignores this, but
+	// it is still used in signing requests, so it must agree with the region
+	// MinIO is configured with, which is us-east-1 by default.
+	Region string
+	// AccessKeyID is the ID of the access key used for signing requests.
+	AccessKeyID string
+	// SecretAccessKey is the secret access key used for signing requests.
+	SecretAccessKey string
+}
+
+// s3Store is an implementation of the Store interface that stores payloads as
+// objects in a bucket using an S3-compatible API, such as that of Amazon S3 or
+// MinIO. URLs returned by this implementation are of the form
+// s3://<bucket>/<key>. Requests use path-style addressing, since that is what
+// MinIO supports by default.
+type s3Store struct {
+	config     S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	// nowFn is overridable for testing purposes
+	nowFn func() time.Time
+}
+
+// NewS3Store returns an implementation of the Store interface that stores
+// payloads as objects in a bucket using an S3-compatible API, such as that of
+// Amazon S3 or MinIO.
+func NewS3Store(config S3Config) (Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("S3 payload store has no endpoint")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing endpoint %s", config.Endpoint)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, errors.Errorf(
+			"S3 payload store endpoint %s does not begin with http:// or https://",
+			config.Endpoint,
+		)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("S3 payload store has no bucket")
+	}
+	if config.Region == "" {
+		return nil, errors.New("S3 payload store has no region")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 payload store has incomplete credentials")
+	}
+	return &s3Store{
+		config:     config,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		nowFn:      time.Now,
+	}, nil
+}
+
+func (s *s3Store) Put(
+	ctx context.Context,
+	key string,
+	payload []byte,
+) (string, error) {
+	if _, err := s.do(ctx, http.MethodPut, key, payload); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", s.config.Bucket, key), nil
+}
+
+func (s *s3Store) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing URL %s", rawURL)
+	}
+	if u.Scheme != "s3" || u.Host != s.config.Bucket {
+		return nil, errors.Errorf(
+			"URL %s does not reference an object in bucket %s",
+			rawURL,
+			s.config.Bucket,
+		)
+	}
+	return s.do(ctx, http.MethodGet, strings.TrimPrefix(u.Path, "/"), nil)
+}
+
+// do sends a signed request having the specified method and body for the
+// object having the specified key and returns the response body.
+func (s *s3Store) do(
+	ctx context.Context,
+	method string,
+	key string,
+	body []byte,
+) ([]byte, error) {
+	u := *s.endpoint
+	u.Path = fmt.Sprintf(
+		"%s/%s/%s",
+		strings.TrimSuffix(s.endpoint.Path, "/"),
+		s.config.Bucket,
+		key,
+	)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		u.String(),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating request for %s", u.String())
+	}
+	s.sign(req, body)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error sending request to %s", u.String())
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading response from %s", u.String())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.Errorf(
+			"received %d from %s %s: %s",
+			resp.StatusCode,
+			method,
+			u.String(),
+			string(respBody),
+		)
+	}
+	return respBody, nil
+}
+
+// sign signs the specified request, having the specified body, using AWS
+// Signature Version 4, as described in the "Authenticating Requests (AWS
+// Signature Version 4)" section of the Amazon S3 API reference.
+func (s *s3Store) sign(req *http.Request, body []byte) {
+	now := s.nowFn().UTC()
+	amzDate := now.Format(s3DateFormat)
+	scopeDate := now.Format(s3ScopeDateFormat)
+	bodySum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodySum[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join(
+		[]string{
+			req.Method,
+			req.URL.EscapedPath(),
+			req.URL.Query().Encode(),
+			fmt.Sprintf("host:%s\n", req.URL.Host) +
+				fmt.Sprintf("x-amz-content-sha256:%s\n", payloadHash) +
+				fmt.Sprintf("x-amz-date:%s\n", amzDate),
+			signedHeaders,
+			payloadHash,
+		},
+		"\n",
+	)
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", scopeDate, s.config.Region)
+	canonicalRequestSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join(
+		[]string{
+			"AWS4-HMAC-SHA256",
+			amzDate,
+			scope,
+			hex.EncodeToString(canonicalRequestSum[:]),
+		},
+		"\n",
+	)
+	signingKey := hmacSHA256(
+		[]byte(fmt.Sprintf("AWS4%s", s.config.SecretAccessKey)),
+		scopeDate,
+	)
+	for _, value := range []string{s.config.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, value)
+	}
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			s.config.AccessKeyID,
+			scope,
+			signedHeaders,
+			hex.EncodeToString(hmacSHA256(signingKey, stringToSign)),
+		),
+	)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the specified data using the specified
+// key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data)) // nolint: errcheck
+	return h.Sum(nil)
+}
@@ -0,0 +1,110 @@
+package payloads
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// referenceKey is the key under which a Reference is found in the payload of
+// an event whose original payload was offloaded to a Store.
+const referenceKey = "brigade_payload_ref"
+
+// Store is an interface for components that can store and retrieve event
+// payloads that are too large to be stored verbatim in Brigade's database.
+type Store interface {
+	// Put stores the specified payload under the specified key and returns a URL
+	// that can later be passed to Get to retrieve it.
+	Put(ctx context.Context, key string, payload []byte) (string, error)
+	// Get retrieves the payload referenced by the specified URL.
+	Get(ctx context.Context, url string) ([]byte, error)
+}
+
+// Reference references a payload that was offloaded to a Store.
+type Reference struct {
+	// URL is the URL that can be passed to the Store's Get function to retrieve
+	// the payload.
+	URL string `json:"url"`
+	// SHA256 is the hex-encoded SHA-256 checksum of the payload.
+	SHA256 string `json:"sha256"`
+	// Size is the size of the payload in bytes.
+	Size int `json:"size"`
+}
+
+// Offload stores the specified payload in the specified Store and returns a
+// small JSON document referencing it, suitable for use as an event's payload
+// in its place. Payloads are stored under a key derived from their checksum,
+// so storing the same payload more than once is harmless.
+func Offload(ctx context.Context, store Store, payload string) (string, error) {
+	sum := sha256.Sum256([]byte(payload))
+	checksum := hex.EncodeToString(sum[:])
+	url, err := store.Put(ctx, fmt.Sprintf("%s.json", checksum), []byte(payload))
+	if err != nil {
+		return "", errors.Wrap(err, "error storing payload")
+	}
+	refBytes, err := json.Marshal(
+		map[string]Reference{
+			referenceKey: {
+				URL:    url,
+				SHA256: checksum,
+				Size:   len(payload),
+			},
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshaling payload reference")
+	}
+	return string(refBytes), nil
+}
+
+// GetReference returns the Reference found in the specified event payload. If
+// the payload does not reference a payload that was offloaded to a Store, nil
+// is returned.
+func GetReference(payload string) *Reference {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return nil
+	}
+	refBytes, ok := doc[referenceKey]
+	if !ok || len(doc) != 1 {
+		return nil
+	}
+	ref := &Reference{}
+	if err := json.Unmarshal(refBytes, ref); err != nil || ref.URL == "" {
+		return nil
+	}
+	return ref
+}
+
+// Fetch returns the original payload of an event having the specified payload.
+// If the specified payload references a payload that was offloaded to a Store,
+// the original payload is retrieved from the specified Store and its checksum
+// is verified. Otherwise, the specified payload is returned unmodified. This
+// permits Go consumers of events to handle all event payloads uniformly,
+// regardless of whether they were offloaded. JavaScript and TypeScript scripts
+// must retrieve offloaded payloads on their own. See docs/EVENT_REFERENCE.md.
+func Fetch(ctx context.Context, store Store, payload string) (string, error) {
+	ref := GetReference(payload)
+	if ref == nil {
+		return payload, nil
+	}
+	payloadBytes, err := store.Get(ctx, ref.URL)
+	if err != nil {
+		return "", errors.Wrapf(err, "error retrieving payload from %s", ref.URL)
+	}
+	sum := sha256.Sum256(payloadBytes)
+	if checksum := hex.EncodeToString(sum[:]); checksum != ref.SHA256 {
+		return "", errors.Errorf(
+			"checksum %s of payload retrieved from %s does not match expected "+
+				"checksum %s",
+			checksum,
+			ref.URL,
+			ref.SHA256,
+		)
+	}
+	return string(payloadBytes), nil
+}
@@ -0,0 +1,199 @@
+package payloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// mockStore is an in-memory implementation of the Store interface.
+type mockStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (m *mockStore) Put(
+	_ context.Context,
+	key string,
+	payload []byte,
+) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if m.objects == nil {
+		m.objects = map[string][]byte{}
+	}
+	url := "mock://" + key
+	m.objects[url] = payload
+	return url, nil
+}
+
+func (m *mockStore) Get(_ context.Context, url string) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	payload, ok := m.objects[url]
+	if !ok {
+		return nil, errors.Errorf("%s not found", url)
+	}
+	return payload, nil
+}
+
+func TestOffload(t *testing.T) {
+	const testPayload = `{"foo":"bar"}`
+	const testChecksum = "7a38bf81f383f69433ad6e900d35b3e2385593f76a7b7ab5d4355b8ba41ee24b" // nolint: lll
+	testCases := []struct {
+		name       string
+		store      *mockStore
+		assertions func(*mockStore, string, error)
+	}{
+		{
+			name: "error storing payload",
+			store: &mockStore{
+				err: errors.New("something went wrong"),
+			},
+			assertions: func(_ *mockStore, _ string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "something went wrong")
+				require.Contains(t, err.Error(), "error storing payload")
+			},
+		},
+		{
+			name:  "success",
+			store: &mockStore{},
+			assertions: func(store *mockStore, ref string, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					`{"brigade_payload_ref":{"url":"mock://`+testChecksum+`.json",`+
+						`"sha256":"`+testChecksum+`","size":13}}`,
+					ref,
+				)
+				require.Equal(
+					t,
+					[]byte(testPayload),
+					store.objects["mock://"+testChecksum+".json"],
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ref, err := Offload(context.Background(), testCase.store, testPayload)
+			testCase.assertions(testCase.store, ref, err)
+		})
+	}
+}
+
+func TestGetReference(t *testing.T) {
+	testCases := []struct {
+		name        string
+		payload     string
+		expectedRef *Reference
+	}{
+		{
+			name:    "payload is not JSON",
+			payload: "foo",
+		},
+		{
+			name:    "payload is not a JSON object",
+			payload: `["foo"]`,
+		},
+		{
+			name:    "payload does not contain a reference",
+			payload: `{"foo":"bar"}`,
+		},
+		{
+			name:    "payload contains a reference and other fields",
+			payload: `{"brigade_payload_ref":{"url":"mock://foo"},"foo":"bar"}`,
+		},
+		{
+			name:    "reference has no URL",
+			payload: `{"brigade_payload_ref":{"sha256":"abc"}}`,
+		},
+		{
+			name:    "payload is a reference",
+			payload: `{"brigade_payload_ref":{"url":"mock://foo","sha256":"abc","size":3}}`, // nolint: lll
+			expectedRef: &Reference{
+				URL:    "mock://foo",
+				SHA256: "abc",
+				Size:   3,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expectedRef, GetReference(testCase.payload))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	const testPayload = `{"foo":"bar"}`
+	testCases := []struct {
+		name       string
+		setup      func(*mockStore) string
+		assertions func(string, error)
+	}{
+		{
+			name: "payload is not a reference",
+			setup: func(*mockStore) string {
+				return testPayload
+			},
+			assertions: func(payload string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, testPayload, payload)
+			},
+		},
+		{
+			name: "error retrieving payload",
+			setup: func(*mockStore) string {
+				return `{"brigade_payload_ref":{"url":"mock://foo"}}`
+			},
+			assertions: func(_ string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error retrieving payload")
+			},
+		},
+		{
+			name: "checksum mismatch",
+			setup: func(store *mockStore) string {
+				ref, err := Offload(context.Background(), store, testPayload)
+				require.NoError(t, err)
+				for url := range store.objects {
+					store.objects[url] = []byte(`{"foo":"baz"}`)
+				}
+				return ref
+			},
+			assertions: func(_ string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not match expected checksum")
+			},
+		},
+		{
+			name: "success",
+			setup: func(store *mockStore) string {
+				ref, err := Offload(context.Background(), store, testPayload)
+				require.NoError(t, err)
+				return ref
+			},
+			assertions: func(payload string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, testPayload, payload)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store := &mockStore{}
+			payload, err := Fetch(
+				context.Background(),
+				store,
+				testCase.setup(store),
+			)
+			testCase.assertions(payload, err)
+		})
+	}
+}
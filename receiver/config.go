@@ -9,6 +9,7 @@ import (
 	"github.com/brigadecore/brigade-foundations/http"
 	"github.com/brigadecore/brigade-foundations/os"
 	"github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade-github-gateway/payloads"
 	"github.com/brigadecore/brigade-github-gateway/receiver/internal/webhooks"
 	"github.com/brigadecore/brigade/sdk/v3/restmachinery"
 	"github.com/pkg/errors"
@@ -40,6 +41,16 @@ func webhookServiceConfig() (webhooks.ServiceConfig, error) {
 			"CHECK_SUITE_ALLOWED_AUTHOR_ASSOCIATIONS",
 			[]string{},
 		),
+		CheckSuiteOKToTestLabel: os.GetEnvVar("CHECK_SUITE_OK_TO_TEST_LABEL", ""),
+		SkipCIMarkers: os.GetStringSliceFromEnvVar(
+			"SKIP_CI_MARKERS",
+			[]string{},
+		),
+		SkipCICheckRunName: os.GetEnvVar("SKIP_CI_CHECK_RUN_NAME", ""),
+		WorkflowRunTriggers: os.GetStringSliceFromEnvVar(
+			"WORKFLOW_RUN_TRIGGERS",
+			[]string{},
+		),
 	}
 	var err error
 	githubAppsPath, err := os.GetRequiredEnvVar("GITHUB_APPS_PATH")
@@ -64,9 +75,244 @@ func webhookServiceConfig() (webhooks.ServiceConfig, error) {
 	for _, githubApp := range githubApps {
 		config.GitHubApps[githubApp.AppID] = githubApp
 	}
+	config.CheckSuiteSkipDraftPRs, err =
+		os.GetBoolFromEnvVar("CHECK_SUITE_SKIP_DRAFT_PRS", false)
+	if err != nil {
+		return config, err
+	}
+	config.RichLabelsEnabled, err =
+		os.GetBoolFromEnvVar("RICH_LABELS_ENABLED", false)
+	if err != nil {
+		return config, err
+	}
+	config.PackageCDEnabled, err =
+		os.GetBoolFromEnvVar("PACKAGE_CD_ENABLED", false)
+	if err != nil {
+		return config, err
+	}
+	config.SupersedeTrackedEvents, err =
+		os.GetBoolFromEnvVar("SUPERSEDE_TRACKED_EVENTS", false)
+	if err != nil {
+		return config, err
+	}
+	config.PRCloneURLSource = webhooks.PRCloneURLSource(
+		os.GetEnvVar("PR_CLONE_URL_SOURCE", string(webhooks.PRCloneURLSourceBase)),
+	)
+	if config.PRCloneURLSource != webhooks.PRCloneURLSourceBase &&
+		config.PRCloneURLSource != webhooks.PRCloneURLSourceHead {
+		return config, errors.Errorf(
+			"invalid value %q for PR_CLONE_URL_SOURCE; valid values are %q and %q",
+			config.PRCloneURLSource,
+			webhooks.PRCloneURLSourceBase,
+			webhooks.PRCloneURLSourceHead,
+		)
+	}
+	config.PRCloneURLProtocol = webhooks.PRCloneURLProtocol(
+		os.GetEnvVar(
+			"PR_CLONE_URL_PROTOCOL",
+			string(webhooks.PRCloneURLProtocolHTTPS),
+		),
+	)
+	if config.PRCloneURLProtocol != webhooks.PRCloneURLProtocolHTTPS &&
+		config.PRCloneURLProtocol != webhooks.PRCloneURLProtocolSSH {
+		return config, errors.Errorf(
+			"invalid value %q for PR_CLONE_URL_PROTOCOL; valid values are %q and %q",
+			config.PRCloneURLProtocol,
+			webhooks.PRCloneURLProtocolHTTPS,
+			webhooks.PRCloneURLProtocolSSH,
+		)
+	}
+	config.PayloadFormat = webhooks.PayloadFormat(
+		os.GetEnvVar("PAYLOAD_FORMAT", string(webhooks.PayloadFormatGitHub)),
+	)
+	if config.PayloadFormat != webhooks.PayloadFormatGitHub &&
+		config.PayloadFormat != webhooks.PayloadFormatCloudEvents {
+		return config, errors.Errorf(
+			"invalid value %q for PAYLOAD_FORMAT; valid values are %q and %q",
+			config.PayloadFormat,
+			webhooks.PayloadFormatGitHub,
+			webhooks.PayloadFormatCloudEvents,
+		)
+	}
+	config.NormalizedPayloadMode = webhooks.NormalizedPayloadMode(
+		os.GetEnvVar(
+			"NORMALIZED_PAYLOAD_MODE",
+			string(webhooks.NormalizedPayloadModeDisabled),
+		),
+	)
+	if config.NormalizedPayloadMode != webhooks.NormalizedPayloadModeDisabled &&
+		config.NormalizedPayloadMode != webhooks.NormalizedPayloadModePayload &&
+		config.NormalizedPayloadMode != webhooks.NormalizedPayloadModeSidecar {
+		return config, errors.Errorf(
+			"invalid value %q for NORMALIZED_PAYLOAD_MODE; valid values are %q, "+
+				"%q, and %q",
+			config.NormalizedPayloadMode,
+			webhooks.NormalizedPayloadModeDisabled,
+			webhooks.NormalizedPayloadModePayload,
+			webhooks.NormalizedPayloadModeSidecar,
+		)
+	}
+	// A CloudEvents envelope's data is the original webhook's payload, so it
+	// cannot be replaced by a normalized payload.
+	if config.PayloadFormat == webhooks.PayloadFormatCloudEvents &&
+		config.NormalizedPayloadMode == webhooks.NormalizedPayloadModePayload {
+		return config, errors.Errorf(
+			"invalid value %q for NORMALIZED_PAYLOAD_MODE when PAYLOAD_FORMAT is "+
+				"%q; valid values are %q and %q",
+			config.NormalizedPayloadMode,
+			config.PayloadFormat,
+			webhooks.NormalizedPayloadModeDisabled,
+			webhooks.NormalizedPayloadModeSidecar,
+		)
+	}
+	if config.PayloadStore, err = payloadStore(); err != nil {
+		return config, err
+	}
+	if config.PayloadStoreThreshold, err =
+		os.GetIntFromEnvVar("PAYLOAD_STORE_THRESHOLD", 65536); err != nil {
+		return config, err
+	}
+	if config.PayloadStoreThreshold <= 0 {
+		return config, errors.Errorf(
+			"invalid value %d for PAYLOAD_STORE_THRESHOLD; value must be greater "+
+				"than zero",
+			config.PayloadStoreThreshold,
+		)
+	}
+	config.ComponentEventsEnabled, err =
+		os.GetBoolFromEnvVar("COMPONENT_EVENTS_ENABLED", false)
+	if err != nil {
+		return config, err
+	}
+	componentMappingsPath := os.GetEnvVar("COMPONENT_MAPPINGS_PATH", "")
+	if componentMappingsPath != "" {
+		if err = readJSONFile(
+			componentMappingsPath,
+			&config.ComponentMappings,
+		); err != nil {
+			return config, err
+		}
+		for _, mapping := range config.ComponentMappings {
+			if err = mapping.Validate(); err != nil {
+				return config, err
+			}
+		}
+	}
+	senderFiltersPath := os.GetEnvVar("SENDER_FILTERS_PATH", "")
+	if senderFiltersPath != "" {
+		if err =
+			readJSONFile(senderFiltersPath, &config.SenderFilters); err != nil {
+			return config, err
+		}
+		for _, filter := range config.SenderFilters {
+			if err = filter.Validate(); err != nil {
+				return config, err
+			}
+		}
+	}
+	dispatchPoliciesPath := os.GetEnvVar("DISPATCH_POLICIES_PATH", "")
+	if dispatchPoliciesPath != "" {
+		if err = readJSONFile(
+			dispatchPoliciesPath,
+			&config.DispatchPolicies,
+		); err != nil {
+			return config, err
+		}
+		for _, policy := range config.DispatchPolicies {
+			if err = policy.Validate(); err != nil {
+				return config, err
+			}
+		}
+	}
+	ciCDMappingsPath := os.GetEnvVar("CI_CD_MAPPINGS_PATH", "")
+	if ciCDMappingsPath != "" {
+		if err =
+			readJSONFile(ciCDMappingsPath, &config.CICDMappings); err != nil {
+			return config, err
+		}
+		for _, mapping := range config.CICDMappings {
+			if err = mapping.Validate(); err != nil {
+				return config, err
+			}
+		}
+	}
+	policiesPath := os.GetEnvVar("CHECK_SUITE_POLICIES_PATH", "")
+	if policiesPath != "" {
+		if err =
+			readJSONFile(policiesPath, &config.CheckSuitePolicies); err != nil {
+			return config, err
+		}
+		for _, policy := range config.CheckSuitePolicies {
+			if err = policy.Validate(); err != nil {
+				return config, err
+			}
+		}
+	}
 	return config, nil
 }
 
+// payloadStore returns the store, if any, to which large event payloads
+// should be offloaded, as specified by environment variables.
+func payloadStore() (payloads.Store, error) {
+	switch storeType := os.GetEnvVar("PAYLOAD_STORE", "none"); storeType {
+	case "none":
+		return nil, nil
+	case "filesystem":
+		path, err := os.GetRequiredEnvVar("PAYLOAD_STORE_PATH")
+		if err != nil {
+			return nil, err
+		}
+		return payloads.NewFilesystemStore(path)
+	case "s3":
+		config := payloads.S3Config{
+			Region: os.GetEnvVar("PAYLOAD_STORE_S3_REGION", "us-east-1"),
+		}
+		var err error
+		if config.Endpoint, err =
+			os.GetRequiredEnvVar("PAYLOAD_STORE_S3_ENDPOINT"); err != nil {
+			return nil, err
+		}
+		if config.Bucket, err =
+			os.GetRequiredEnvVar("PAYLOAD_STORE_S3_BUCKET"); err != nil {
+			return nil, err
+		}
+		if config.AccessKeyID, err =
+			os.GetRequiredEnvVar("PAYLOAD_STORE_S3_ACCESS_KEY_ID"); err != nil {
+			return nil, err
+		}
+		if config.SecretAccessKey, err =
+			os.GetRequiredEnvVar("PAYLOAD_STORE_S3_SECRET_ACCESS_KEY"); err != nil {
+			return nil, err
+		}
+		return payloads.NewS3Store(config)
+	default:
+		return nil, errors.Errorf(
+			"invalid value %q for PAYLOAD_STORE; valid values are %q, %q, and %q",
+			storeType,
+			"none",
+			"filesystem",
+			"s3",
+		)
+	}
+}
+
+// readJSONFile unmarshals the contents of the JSON file at the specified path
+// into the object pointed to by obj.
+func readJSONFile(path string, obj interface{}) error {
+	exists, err := file.Exists(path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("file %s does not exist", path)
+	}
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(fileBytes, obj)
+}
+
 // signatureVerificationFilterConfig populates configuration for the signature
 // verification filter from environment variables.
 func signatureVerificationFilterConfig() (
@@ -177,6 +177,644 @@ func TestWebHookServiceConfig(t *testing.T) {
 				)
 			},
 		},
+		{
+			name: "CHECK_SUITE_OK_TO_TEST_LABEL defined",
+			setup: func() {
+				t.Setenv("CHECK_SUITE_OK_TO_TEST_LABEL", "ok-to-test")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "ok-to-test", config.CheckSuiteOKToTestLabel)
+			},
+		},
+		{
+			name: "SKIP_CI_MARKERS and SKIP_CI_CHECK_RUN_NAME defined",
+			setup: func() {
+				t.Setenv("SKIP_CI_MARKERS", "[skip brigade],[brigade skip]")
+				t.Setenv("SKIP_CI_CHECK_RUN_NAME", "brigade")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{"[skip brigade]", "[brigade skip]"},
+					config.SkipCIMarkers,
+				)
+				require.Equal(t, "brigade", config.SkipCICheckRunName)
+			},
+		},
+		{
+			name: "WORKFLOW_RUN_TRIGGERS defined",
+			setup: func() {
+				t.Setenv("WORKFLOW_RUN_TRIGGERS", "build,package")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{"build", "package"},
+					config.WorkflowRunTriggers,
+				)
+			},
+		},
+		{
+			name: "CHECK_SUITE_SKIP_DRAFT_PRS not a bool",
+			setup: func() {
+				t.Setenv("CHECK_SUITE_SKIP_DRAFT_PRS", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as a bool")
+				require.Contains(t, err.Error(), "CHECK_SUITE_SKIP_DRAFT_PRS")
+			},
+		},
+		{
+			name: "CHECK_SUITE_SKIP_DRAFT_PRS defined",
+			setup: func() {
+				t.Setenv("CHECK_SUITE_SKIP_DRAFT_PRS", "true")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.True(t, config.CheckSuiteSkipDraftPRs)
+			},
+		},
+		{
+			name: "RICH_LABELS_ENABLED not a bool",
+			setup: func() {
+				t.Setenv("RICH_LABELS_ENABLED", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as a bool")
+				require.Contains(t, err.Error(), "RICH_LABELS_ENABLED")
+			},
+		},
+		{
+			name: "RICH_LABELS_ENABLED defined",
+			setup: func() {
+				t.Setenv("RICH_LABELS_ENABLED", "true")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.True(t, config.RichLabelsEnabled)
+			},
+		},
+		{
+			name: "PACKAGE_CD_ENABLED not a bool",
+			setup: func() {
+				t.Setenv("PACKAGE_CD_ENABLED", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as a bool")
+				require.Contains(t, err.Error(), "PACKAGE_CD_ENABLED")
+			},
+		},
+		{
+			name: "PACKAGE_CD_ENABLED defined",
+			setup: func() {
+				t.Setenv("PACKAGE_CD_ENABLED", "true")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.True(t, config.PackageCDEnabled)
+			},
+		},
+		{
+			name: "SUPERSEDE_TRACKED_EVENTS not a bool",
+			setup: func() {
+				t.Setenv("SUPERSEDE_TRACKED_EVENTS", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as a bool")
+				require.Contains(t, err.Error(), "SUPERSEDE_TRACKED_EVENTS")
+			},
+		},
+		{
+			name: "SUPERSEDE_TRACKED_EVENTS defined",
+			setup: func() {
+				t.Setenv("SUPERSEDE_TRACKED_EVENTS", "true")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.True(t, config.SupersedeTrackedEvents)
+			},
+		},
+		{
+			name: "PR_CLONE_URL_SOURCE invalid",
+			setup: func() {
+				t.Setenv("PR_CLONE_URL_SOURCE", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "PR_CLONE_URL_SOURCE")
+			},
+		},
+		{
+			name: "PR_CLONE_URL_SOURCE defined",
+			setup: func() {
+				t.Setenv("PR_CLONE_URL_SOURCE", "head")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					webhooks.PRCloneURLSourceHead,
+					config.PRCloneURLSource,
+				)
+			},
+		},
+		{
+			name: "PR_CLONE_URL_PROTOCOL invalid",
+			setup: func() {
+				t.Setenv("PR_CLONE_URL_PROTOCOL", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "PR_CLONE_URL_PROTOCOL")
+			},
+		},
+		{
+			name: "PR_CLONE_URL_PROTOCOL defined",
+			setup: func() {
+				t.Setenv("PR_CLONE_URL_PROTOCOL", "ssh")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					webhooks.PRCloneURLProtocolSSH,
+					config.PRCloneURLProtocol,
+				)
+			},
+		},
+		{
+			name: "PAYLOAD_FORMAT invalid",
+			setup: func() {
+				t.Setenv("PAYLOAD_FORMAT", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "PAYLOAD_FORMAT")
+			},
+		},
+		{
+			name: "PAYLOAD_FORMAT defined",
+			setup: func() {
+				t.Setenv("PAYLOAD_FORMAT", "cloudevents")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					webhooks.PayloadFormatCloudEvents,
+					config.PayloadFormat,
+				)
+			},
+		},
+		{
+			name: "NORMALIZED_PAYLOAD_MODE invalid",
+			setup: func() {
+				t.Setenv("NORMALIZED_PAYLOAD_MODE", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "NORMALIZED_PAYLOAD_MODE")
+			},
+		},
+		{
+			name: "NORMALIZED_PAYLOAD_MODE incompatible with PAYLOAD_FORMAT",
+			setup: func() {
+				t.Setenv("PAYLOAD_FORMAT", "cloudevents")
+				t.Setenv("NORMALIZED_PAYLOAD_MODE", "payload")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "NORMALIZED_PAYLOAD_MODE")
+				require.Contains(t, err.Error(), "PAYLOAD_FORMAT")
+			},
+		},
+		{
+			name: "NORMALIZED_PAYLOAD_MODE defined",
+			setup: func() {
+				t.Setenv("NORMALIZED_PAYLOAD_MODE", "sidecar")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					webhooks.NormalizedPayloadModeSidecar,
+					config.NormalizedPayloadMode,
+				)
+			},
+		},
+		{
+			name: "PAYLOAD_STORE invalid",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "PAYLOAD_STORE")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_PATH not set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE", "filesystem")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "value not found for")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_PATH")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_PATH set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_PATH", "/var/lib/payloads")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, config.PayloadStore)
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_S3_ENDPOINT not set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE", "s3")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "value not found for")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_S3_ENDPOINT")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_S3_BUCKET not set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_S3_ENDPOINT", "http://minio:9000")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "value not found for")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_S3_BUCKET")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_S3_ACCESS_KEY_ID not set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_S3_BUCKET", "payloads")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "value not found for")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_S3_ACCESS_KEY_ID")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_S3_SECRET_ACCESS_KEY not set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_S3_ACCESS_KEY_ID", "foo")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "value not found for")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_S3_SECRET_ACCESS_KEY")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_S3_SECRET_ACCESS_KEY set",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_S3_SECRET_ACCESS_KEY", "bar")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, config.PayloadStore)
+				require.Equal(t, 65536, config.PayloadStoreThreshold)
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_THRESHOLD not an int",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_THRESHOLD", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as an int")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_THRESHOLD")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_THRESHOLD not positive",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_THRESHOLD", "0")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "must be greater than zero")
+				require.Contains(t, err.Error(), "PAYLOAD_STORE_THRESHOLD")
+			},
+		},
+		{
+			name: "PAYLOAD_STORE_THRESHOLD defined",
+			setup: func() {
+				t.Setenv("PAYLOAD_STORE_THRESHOLD", "1024")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1024, config.PayloadStoreThreshold)
+			},
+		},
+		{
+			name: "COMPONENT_EVENTS_ENABLED not a bool",
+			setup: func() {
+				t.Setenv("COMPONENT_EVENTS_ENABLED", "nope")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "was not parsable as a bool")
+				require.Contains(t, err.Error(), "COMPONENT_EVENTS_ENABLED")
+			},
+		},
+		{
+			name: "COMPONENT_EVENTS_ENABLED defined",
+			setup: func() {
+				t.Setenv("COMPONENT_EVENTS_ENABLED", "true")
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.True(t, config.ComponentEventsEnabled)
+			},
+		},
+		{
+			name: "COMPONENT_MAPPINGS_PATH path does not exist",
+			setup: func() {
+				t.Setenv("COMPONENT_MAPPINGS_PATH", "/completely/bogus/path")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"file /completely/bogus/path does not exist",
+				)
+			},
+		},
+		{
+			name: "COMPONENT_MAPPINGS_PATH contains an invalid mapping",
+			setup: func() {
+				mappingsFile, err := ioutil.TempFile("", "mappings.json")
+				require.NoError(t, err)
+				defer mappingsFile.Close()
+				_, err = mappingsFile.Write([]byte(`[{"component":"foo"}]`))
+				require.NoError(t, err)
+				t.Setenv("COMPONENT_MAPPINGS_PATH", mappingsFile.Name())
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any paths")
+			},
+		},
+		{
+			name: "COMPONENT_MAPPINGS_PATH contains valid mappings",
+			setup: func() {
+				mappingsFile, err := ioutil.TempFile("", "mappings.json")
+				require.NoError(t, err)
+				defer mappingsFile.Close()
+				_, err = mappingsFile.Write(
+					[]byte(`[{"component":"foo","paths":["foo/**"]}]`),
+				)
+				require.NoError(t, err)
+				t.Setenv("COMPONENT_MAPPINGS_PATH", mappingsFile.Name())
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]webhooks.ComponentMapping{
+						{
+							Component: "foo",
+							Paths:     []string{"foo/**"},
+						},
+					},
+					config.ComponentMappings,
+				)
+			},
+		},
+		{
+			name: "SENDER_FILTERS_PATH path does not exist",
+			setup: func() {
+				t.Setenv("SENDER_FILTERS_PATH", "/completely/bogus/path")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"file /completely/bogus/path does not exist",
+				)
+			},
+		},
+		{
+			name: "SENDER_FILTERS_PATH contains an invalid filter",
+			setup: func() {
+				filtersFile, err := ioutil.TempFile("", "filters.json")
+				require.NoError(t, err)
+				defer filtersFile.Close()
+				_, err = filtersFile.Write([]byte(`[{"name":"foo"}]`))
+				require.NoError(t, err)
+				t.Setenv("SENDER_FILTERS_PATH", filtersFile.Name())
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any senders")
+			},
+		},
+		{
+			name: "SENDER_FILTERS_PATH contains valid filters",
+			setup: func() {
+				filtersFile, err := ioutil.TempFile("", "filters.json")
+				require.NoError(t, err)
+				defer filtersFile.Close()
+				_, err = filtersFile.Write([]byte(`[{"name":"foo","bots":true}]`))
+				require.NoError(t, err)
+				t.Setenv("SENDER_FILTERS_PATH", filtersFile.Name())
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]webhooks.SenderFilter{
+						{
+							Name: "foo",
+							Bots: true,
+						},
+					},
+					config.SenderFilters,
+				)
+			},
+		},
+		{
+			name: "DISPATCH_POLICIES_PATH path does not exist",
+			setup: func() {
+				t.Setenv("DISPATCH_POLICIES_PATH", "/completely/bogus/path")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"file /completely/bogus/path does not exist",
+				)
+			},
+		},
+		{
+			name: "DISPATCH_POLICIES_PATH contains an invalid policy",
+			setup: func() {
+				policiesFile, err := ioutil.TempFile("", "dispatch-policies.json")
+				require.NoError(t, err)
+				defer policiesFile.Close()
+				_, err = policiesFile.Write([]byte(`[{"name":"foo"}]`))
+				require.NoError(t, err)
+				t.Setenv("DISPATCH_POLICIES_PATH", policiesFile.Name())
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any repos")
+			},
+		},
+		{
+			name: "DISPATCH_POLICIES_PATH contains valid policies",
+			setup: func() {
+				policiesFile, err := ioutil.TempFile("", "dispatch-policies.json")
+				require.NoError(t, err)
+				defer policiesFile.Close()
+				_, err = policiesFile.Write(
+					[]byte(
+						`[{"name":"foo","repos":["brigadecore"],"eventTypes":["deploy"]}]`,
+					),
+				)
+				require.NoError(t, err)
+				t.Setenv("DISPATCH_POLICIES_PATH", policiesFile.Name())
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]webhooks.DispatchPolicy{
+						{
+							Name:       "foo",
+							Repos:      []string{"brigadecore"},
+							EventTypes: []string{"deploy"},
+						},
+					},
+					config.DispatchPolicies,
+				)
+			},
+		},
+		{
+			name: "CI_CD_MAPPINGS_PATH contains an invalid mapping",
+			setup: func() {
+				mappingsFile, err := ioutil.TempFile("", "ci-cd-mappings.json")
+				require.NoError(t, err)
+				defer mappingsFile.Close()
+				_, err = mappingsFile.Write([]byte(`[{"name":"foo"}]`))
+				require.NoError(t, err)
+				t.Setenv("CI_CD_MAPPINGS_PATH", mappingsFile.Name())
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any event types")
+			},
+		},
+		{
+			name: "CI_CD_MAPPINGS_PATH contains valid mappings",
+			setup: func() {
+				mappingsFile, err := ioutil.TempFile("", "ci-cd-mappings.json")
+				require.NoError(t, err)
+				defer mappingsFile.Close()
+				_, err = mappingsFile.Write(
+					[]byte(
+						`[{"name":"foo","eventTypes":["push"],"type":"cd:pipeline_requested","branches":["main"]}]`, // nolint: lll
+					),
+				)
+				require.NoError(t, err)
+				t.Setenv("CI_CD_MAPPINGS_PATH", mappingsFile.Name())
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]webhooks.CICDMapping{
+						{
+							Name:       "foo",
+							EventTypes: []string{"push"},
+							Type:       "cd:pipeline_requested",
+							Branches:   []string{"main"},
+						},
+					},
+					config.CICDMappings,
+				)
+			},
+		},
+		{
+			name: "CHECK_SUITE_POLICIES_PATH path does not exist",
+			setup: func() {
+				t.Setenv("CHECK_SUITE_POLICIES_PATH", "/completely/bogus/path")
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"file /completely/bogus/path does not exist",
+				)
+			},
+		},
+		{
+			name: "CHECK_SUITE_POLICIES_PATH contains an invalid policy",
+			setup: func() {
+				policiesFile, err := ioutil.TempFile("", "policies.json")
+				require.NoError(t, err)
+				defer policiesFile.Close()
+				_, err = policiesFile.Write([]byte(`[{"name":"foo"}]`))
+				require.NoError(t, err)
+				t.Setenv("CHECK_SUITE_POLICIES_PATH", policiesFile.Name())
+			},
+			assertions: func(_ webhooks.ServiceConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any repos")
+			},
+		},
+		{
+			name: "CHECK_SUITE_POLICIES_PATH contains valid policies",
+			setup: func() {
+				policiesFile, err := ioutil.TempFile("", "policies.json")
+				require.NoError(t, err)
+				defer policiesFile.Close()
+				_, err = policiesFile.Write(
+					[]byte(`[{"name":"foo","repos":["brigadecore"],"allowedAuthorAssociations":["OWNER"]}]`), // nolint: lll
+				)
+				require.NoError(t, err)
+				t.Setenv("CHECK_SUITE_POLICIES_PATH", policiesFile.Name())
+			},
+			assertions: func(config webhooks.ServiceConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]webhooks.CheckSuitePolicy{
+						{
+							Name:                      "foo",
+							Repos:                     []string{"brigadecore"},
+							AllowedAuthorAssociations: []string{"OWNER"},
+						},
+					},
+					config.CheckSuitePolicies,
+				)
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
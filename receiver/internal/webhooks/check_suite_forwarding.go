@@ -2,6 +2,7 @@ package webhooks
 
 import (
 	"context"
+	"log"
 	"strings"
 
 	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
@@ -9,10 +10,15 @@ import (
 	"github.com/pkg/errors"
 )
 
+// checkSuiteForwarding requests a check suite in response to the specified
+// webhook, if the conditions described below are met. If the webhook is for a
+// comment on an issue, getCommentPR must retrieve the PR the comment was made
+// on, if any.
 func (s *service) checkSuiteForwarding(
 	ctx context.Context,
 	appID int64,
 	webhook interface{},
+	getCommentPR func() (*github.PullRequest, error),
 ) error {
 	switch webhook := webhook.(type) {
 
@@ -23,76 +29,242 @@ func (s *service) checkSuiteForwarding(
 		// 1. The issue in question is a PR
 		// 2. The action is "created"
 		// 3. The comment contains "/brig check" or "/brig run" (case insensitive)
-		// 4. The comment's author is allowed to request a check suite
+		// 4. Commands are enabled for the repository
+		// 5. The comment's author is allowed to request a check suite
 		comment := strings.ToLower(webhook.GetComment().GetBody())
-		if webhook.GetIssue().IsPullRequest() && webhook.GetAction() == "created" &&
-			(strings.Contains(comment, "/brig check") || strings.Contains(comment, "/brig run")) && // nolint: lll
-			s.isAllowedAuthorAssociation(webhook.GetComment().GetAuthorAssociation()) { // nolint: lll
-			pr, err := s.getPRFromIssueCommentWebhook(
-				ctx,
-				s.config.GitHubApps[appID],
-				*webhook,
+		if !webhook.GetIssue().IsPullRequest() ||
+			webhook.GetAction() != "created" ||
+			!(strings.Contains(comment, "/brig check") ||
+				strings.Contains(comment, "/brig run")) {
+			return nil
+		}
+		policy := s.getCheckSuitePolicy(webhook.GetRepo().GetFullName())
+		if !policy.commandsEnabled ||
+			!policy.isAllowedAuthorAssociation(
+				webhook.GetComment().GetAuthorAssociation(),
+			) {
+			log.Printf(
+				"not forwarding check suite for %s PR #%d comment; policy %q "+
+					"does not permit it",
+				webhook.GetRepo().GetFullName(),
+				webhook.GetIssue().GetNumber(),
+				policy.name,
 			)
-			if err != nil {
-				return err
-			}
-			if err = s.requestCheckSuite(
-				ctx,
-				s.config.GitHubApps[appID],
-				webhook.GetInstallation().GetID(),
-				webhook.GetRepo().GetOwner().GetLogin(),
-				webhook.GetRepo().GetName(),
-				pr.GetHead().GetSHA(),
-			); err != nil {
-				return err
-			}
+			return nil
+		}
+		log.Printf(
+			"forwarding check suite for %s PR #%d comment; policy %q permits it",
+			webhook.GetRepo().GetFullName(),
+			webhook.GetIssue().GetNumber(),
+			policy.name,
+		)
+		pr, err := getCommentPR()
+		if err != nil {
+			return err
 		}
+		return s.requestCheckSuiteFn(
+			ctx,
+			s.config.GitHubApps[appID],
+			webhook.GetInstallation().GetID(),
+			webhook.GetRepo().GetOwner().GetLogin(),
+			webhook.GetRepo().GetName(),
+			pr.GetHead().GetSHA(),
+		)
 
 	case *github.PullRequestEvent:
 		// Under a very specific set of conditions, we will request a check suite to
 		// run in response to this PR.
 		//
-		// 1. The action is "opened", "synchronize", or "reopened"
+		// 1. The action is "opened", "synchronize", or "reopened" OR the action is
+		//    "labeled" and the label applied is the "ok-to-test" label OR the
+		//    action is "ready_for_review" and draft PRs are skipped for the
+		//    repository
 		// 2. The PR comes from a fork. (If the PR does NOT come from a fork, then
 		//    it comes from a branch in the same repository to which this PR
 		//    belongs. If this is the case, someone necessarily pushed to that
 		//    branch, and pushes automatically trigger check suites. Were we to
 		//    request a check suite at this juncture, it would be a duplicate.)
-		// 3. The PR's author is allowed to request a check suite
+		// 3. PRs from forks are forwarded for the repository
+		// 4. The PR is not a draft OR draft PRs are not skipped for the repository
+		// 5. The PR's author is allowed to request a check suite OR a maintainer
+		//    has vouched for the PR by applying the "ok-to-test" label
+		// 6. The PR's title does not contain a marker requesting that CI be
+		//    skipped
+		policy := s.getCheckSuitePolicy(webhook.GetRepo().GetFullName())
 		switch webhook.GetAction() {
 		case "opened", "synchronize", "reopened":
-			if webhook.GetPullRequest().GetHead().GetRepo().GetFork() &&
-				s.isAllowedAuthorAssociation(webhook.GetPullRequest().GetAuthorAssociation()) { // nolint: lll
-				if err := s.requestCheckSuite(
-					ctx,
-					s.config.GitHubApps[appID],
-					webhook.GetInstallation().GetID(),
-					webhook.GetRepo().GetOwner().GetLogin(),
-					webhook.GetRepo().GetName(),
-					webhook.GetPullRequest().GetHead().GetSHA(),
-				); err != nil {
-					return err
-				}
+		case "labeled":
+			if s.config.CheckSuiteOKToTestLabel == "" ||
+				webhook.GetLabel().GetName() != s.config.CheckSuiteOKToTestLabel {
+				return nil
+			}
+		case "ready_for_review":
+			// If drafts aren't skipped, a check suite was already requested when the
+			// PR was opened or last updated.
+			if !policy.skipDraftPRs {
+				return nil
 			}
+		default:
+			return nil
+		}
+		if !webhook.GetPullRequest().GetHead().GetRepo().GetFork() {
+			return nil
 		}
+		if policy.skipDraftPRs && webhook.GetPullRequest().GetDraft() {
+			log.Printf(
+				"not forwarding check suite for %s PR #%d; policy %q skips drafts",
+				webhook.GetRepo().GetFullName(),
+				webhook.GetPullRequest().GetNumber(),
+				policy.name,
+			)
+			return nil
+		}
+		allowed := policy.forwardForkPRs &&
+			policy.isAllowedAuthorAssociation(
+				webhook.GetPullRequest().GetAuthorAssociation(),
+			)
+		if !allowed && policy.forwardForkPRs {
+			var err error
+			if allowed, err = s.isOKToTest(ctx, appID, policy, webhook); err != nil {
+				return err
+			}
+		}
+		if !allowed {
+			log.Printf(
+				"not forwarding check suite for %s PR #%d; policy %q does not "+
+					"permit it",
+				webhook.GetRepo().GetFullName(),
+				webhook.GetPullRequest().GetNumber(),
+				policy.name,
+			)
+			return nil
+		}
+		if marker :=
+			s.getSkipCIMarker(webhook.GetPullRequest().GetTitle()); marker != "" {
+			log.Printf(
+				"not forwarding check suite for %s PR #%d; title contains %q",
+				webhook.GetRepo().GetFullName(),
+				webhook.GetPullRequest().GetNumber(),
+				marker,
+			)
+			return nil
+		}
+		log.Printf(
+			"forwarding check suite for %s PR #%d; policy %q permits it",
+			webhook.GetRepo().GetFullName(),
+			webhook.GetPullRequest().GetNumber(),
+			policy.name,
+		)
+		return s.requestCheckSuiteFn(
+			ctx,
+			s.config.GitHubApps[appID],
+			webhook.GetInstallation().GetID(),
+			webhook.GetRepo().GetOwner().GetLogin(),
+			webhook.GetRepo().GetName(),
+			webhook.GetPullRequest().GetHead().GetSHA(),
+		)
 
 	}
 
 	return nil
 }
 
-// isAllowedAuthorAssociation makes a determination whether an author having the
-// specified relationship to a given repository is permitted to have check
-// suites automatically created and executed.
-func (s *service) isAllowedAuthorAssociation(authorAssociation string) bool {
-	for _, a := range s.config.CheckSuiteAllowedAuthorAssociations {
-		if a == authorAssociation {
+// isOKToTest makes a determination whether a maintainer has vouched for a PR
+// by applying the configured "ok-to-test" label to it. Because the label
+// vouches only for code written by the PR's author or by others who are
+// trusted, a push to the PR by anyone else revokes it. A pusher other than the
+// PR's author is trusted if they are allowed to request a check suite under the
+// specified policy and they control the PR's head repository, either by owning
+// it or, as a maintainer, by having been permitted by the PR's author to modify
+// it. When the label is revoked, it is also removed from the PR so that a
+// maintainer must explicitly re-apply it.
+func (s *service) isOKToTest(
+	ctx context.Context,
+	appID int64,
+	policy checkSuitePolicy,
+	pre *github.PullRequestEvent,
+) (bool, error) {
+	label := s.config.CheckSuiteOKToTestLabel
+	if label == "" || !hasLabel(pre.GetPullRequest(), label) {
+		return false, nil
+	}
+	pusher := pre.GetSender().GetLogin()
+	if pre.GetAction() != "synchronize" ||
+		pusher == pre.GetPullRequest().GetUser().GetLogin() {
+		return true, nil
+	}
+	association, err := s.getAuthorAssociationFn(
+		ctx,
+		s.config.GitHubApps[appID],
+		pre.GetInstallation().GetID(),
+		pre.GetRepo().GetOwner().GetLogin(),
+		pre.GetRepo().GetName(),
+		pusher,
+	)
+	if err != nil {
+		return false, err
+	}
+	if policy.isAllowedAuthorAssociation(association) &&
+		(pusher == pre.GetPullRequest().GetHead().GetRepo().GetOwner().GetLogin() ||
+			pre.GetPullRequest().GetMaintainerCanModify()) {
+		return true, nil
+	}
+	log.Printf(
+		"revoking %q label from %s PR #%d; %s (%s) pushed to a PR authored by %s",
+		label,
+		pre.GetRepo().GetFullName(),
+		pre.GetPullRequest().GetNumber(),
+		pusher,
+		association,
+		pre.GetPullRequest().GetUser().GetLogin(),
+	)
+	return false, s.removeLabelFn(
+		ctx,
+		s.config.GitHubApps[appID],
+		pre.GetInstallation().GetID(),
+		pre.GetRepo().GetOwner().GetLogin(),
+		pre.GetRepo().GetName(),
+		pre.GetPullRequest().GetNumber(),
+		label,
+	)
+}
+
+// hasLabel returns a boolean indicating whether the specified
+// github.PullRequest has a label with the specified name.
+func hasLabel(pr *github.PullRequest, name string) bool {
+	for _, label := range pr.Labels {
+		if label.GetName() == name {
 			return true
 		}
 	}
 	return false
 }
 
+// getCommentPRFn returns a function that retrieves the github.PullRequest
+// associated with a given github.IssueCommentEvent. The PR is retrieved the
+// first time the function is called and every subsequent call returns the same
+// result, so that all steps in handling a single webhook can share one lookup.
+func (s *service) getCommentPRFn(
+	ctx context.Context,
+	appID int64,
+	ice *github.IssueCommentEvent,
+) func() (*github.PullRequest, error) {
+	var pr *github.PullRequest
+	var err error
+	var retrieved bool
+	return func() (*github.PullRequest, error) {
+		if !retrieved {
+			pr, err = s.getPRFromIssueCommentWebhookFn(
+				ctx,
+				s.config.GitHubApps[appID],
+				*ice,
+			)
+			retrieved = true
+		}
+		return pr, err
+	}
+}
+
 // getPRFromIssueCommentWebhook retrieves the github.PullRequest associated with
 // a given github.IssueCommentEvent.
 func (s *service) getPRFromIssueCommentWebhook(
@@ -205,3 +377,103 @@ func (s *service) requestCheckSuite(
 		commit,
 	)
 }
+
+// getAuthorAssociation determines the relationship of the user having the
+// specified login to the specified repository. Since webhooks for pushes to PRs
+// do not convey the pusher's author association, this is used in its place.
+// It is one of OWNER, MEMBER, COLLABORATOR, or NONE. Organization membership
+// can only be determined if the GitHub App is permitted to read it. Otherwise,
+// members having access to the repository are regarded as collaborators.
+func (s *service) getAuthorAssociation(
+	ctx context.Context,
+	app ghlib.App,
+	installationID int64,
+	repoOwner string,
+	repoName string,
+	login string,
+) (string, error) {
+	if strings.EqualFold(login, repoOwner) {
+		return "OWNER", nil
+	}
+	ghClient, err := ghlib.NewClient(
+		ctx,
+		app.AppID,
+		installationID,
+		[]byte(app.APIKey),
+	)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"error creating new client for installation %d",
+			installationID,
+		)
+	}
+	// If the repository's owner is not an org, this is simply false.
+	isMember, _, err := ghClient.Organizations.IsMember(ctx, repoOwner, login)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"error determining if %s is a member of %s",
+			login,
+			repoOwner,
+		)
+	}
+	if isMember {
+		return "MEMBER", nil
+	}
+	isCollaborator, _, err :=
+		ghClient.Repositories.IsCollaborator(ctx, repoOwner, repoName, login)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"error determining if %s is a collaborator on %s/%s",
+			login,
+			repoOwner,
+			repoName,
+		)
+	}
+	if isCollaborator {
+		return "COLLABORATOR", nil
+	}
+	return "NONE", nil
+}
+
+// removeLabel removes the specified label from the specified PR.
+func (s *service) removeLabel(
+	ctx context.Context,
+	app ghlib.App,
+	installationID int64,
+	repoOwner string,
+	repoName string,
+	number int,
+	label string,
+) error {
+	ghClient, err := ghlib.NewClient(
+		ctx,
+		app.AppID,
+		installationID,
+		[]byte(app.APIKey),
+	)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"error creating new client for installation %d",
+			installationID,
+		)
+	}
+	_, err = ghClient.Issues.RemoveLabelForIssue(
+		ctx,
+		repoOwner,
+		repoName,
+		number,
+		label,
+	)
+	return errors.Wrapf(
+		err,
+		"error removing label %q from %s/%s PR #%d",
+		label,
+		repoOwner,
+		repoName,
+		number,
+	)
+}
@@ -1,11 +1,478 @@
 package webhooks
 
 import (
+	"context"
 	"testing"
 
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/google/go-github/v33/github"
 	"github.com/stretchr/testify/require"
 )
 
+func TestCheckSuiteForwardingForPRs(t *testing.T) {
+	const testOKToTestLabel = "ok-to-test"
+	testRepo := &github.Repository{
+		FullName: github.String("brigadecore/brigade-github-gateway"),
+		Name:     github.String("brigade-github-gateway"),
+		Owner: &github.User{
+			Login: github.String("brigadecore"),
+		},
+	}
+	testForkRepo := &github.Repository{
+		Fork: github.Bool(true),
+	}
+	testAuthor := &github.User{
+		Login: github.String("krancour"),
+	}
+	testCases := []struct {
+		name               string
+		webhook            *github.PullRequestEvent
+		policies           []CheckSuitePolicy
+		pusherAssociation  string
+		expectForwarded    bool
+		expectLabelRemoved bool
+	}{
+		{
+			name: "action not of interest",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("closed"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+		},
+		{
+			name: "PR not from a fork",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Head:              &github.PullRequestBranch{},
+				},
+			},
+		},
+		{
+			name: "author is allowed",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+			expectForwarded: true,
+		},
+		{
+			name: "author is allowed, but policy disables fork PRs",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+			policies: []CheckSuitePolicy{
+				{
+					Name:           "no-forks",
+					Repos:          []string{"brigadecore"},
+					ForwardForkPRs: boolPtr(false),
+				},
+			},
+		},
+		{
+			name: "author is not allowed",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+		},
+		{
+			name: "some other label applied",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("labeled"),
+				Repo:   testRepo,
+				Label:  &github.Label{Name: github.String("bug")},
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String("bug")},
+					},
+				},
+			},
+		},
+		{
+			name: "ok-to-test label applied",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("labeled"),
+				Repo:   testRepo,
+				Label:  &github.Label{Name: github.String(testOKToTestLabel)},
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String(testOKToTestLabel)},
+					},
+				},
+			},
+			expectForwarded: true,
+		},
+		{
+			name: "push by author to PR with ok-to-test label",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				Sender: testAuthor,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					User:              testAuthor,
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String(testOKToTestLabel)},
+					},
+				},
+			},
+			expectForwarded: true,
+		},
+		{
+			name: "push by someone else to PR with ok-to-test label",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				Sender: &github.User{
+					Login: github.String("someone-else"),
+				},
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					User:              testAuthor,
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String(testOKToTestLabel)},
+					},
+				},
+			},
+			pusherAssociation:  "NONE",
+			expectLabelRemoved: true,
+		},
+		{
+			name: "push by maintainer to contributor's PR with ok-to-test label",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				Sender: &github.User{
+					Login: github.String("maintainer"),
+				},
+				PullRequest: &github.PullRequest{
+					AuthorAssociation:   github.String("NONE"),
+					User:                testAuthor,
+					MaintainerCanModify: github.Bool(true),
+					Head:                &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String(testOKToTestLabel)},
+					},
+				},
+			},
+			pusherAssociation: "OWNER",
+			expectForwarded:   true,
+		},
+		{
+			name: "push by maintainer without control of fork; ok-to-test label",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				Sender: &github.User{
+					Login: github.String("maintainer"),
+				},
+				PullRequest: &github.PullRequest{
+					AuthorAssociation:   github.String("NONE"),
+					User:                testAuthor,
+					MaintainerCanModify: github.Bool(false),
+					Head:                &github.PullRequestBranch{Repo: testForkRepo},
+					Labels: []*github.Label{
+						{Name: github.String(testOKToTestLabel)},
+					},
+				},
+			},
+			pusherAssociation:  "OWNER",
+			expectLabelRemoved: true,
+		},
+		{
+			name: "draft PR opened; drafts not skipped",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Draft:             github.Bool(true),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+			expectForwarded: true,
+		},
+		{
+			name: "draft PR opened; drafts skipped",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Draft:             github.Bool(true),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+			policies: []CheckSuitePolicy{
+				{
+					Name:         "skip-drafts",
+					Repos:        []string{"brigadecore"},
+					SkipDraftPRs: boolPtr(true),
+				},
+			},
+		},
+		{
+			name: "PR ready for review; drafts not skipped",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("ready_for_review"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Draft:             github.Bool(false),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+		},
+		{
+			name: "PR ready for review; drafts skipped",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("ready_for_review"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("OWNER"),
+					Draft:             github.Bool(false),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+			policies: []CheckSuitePolicy{
+				{
+					Name:         "skip-drafts",
+					Repos:        []string{"brigadecore"},
+					SkipDraftPRs: boolPtr(true),
+				},
+			},
+			expectForwarded: true,
+		},
+		{
+			name: "author is allowed, but title requests CI be skipped",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					Title:             github.String("Fix typo [skip ci]"),
+					AuthorAssociation: github.String("OWNER"),
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+		},
+		{
+			name: "push to PR without ok-to-test label",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				Sender: testAuthor,
+				PullRequest: &github.PullRequest{
+					AuthorAssociation: github.String("NONE"),
+					User:              testAuthor,
+					Head:              &github.PullRequestBranch{Repo: testForkRepo},
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var forwarded, labelRemoved bool
+			s := &service{
+				config: ServiceConfig{
+					CheckSuiteAllowedAuthorAssociations: []string{"OWNER"},
+					CheckSuitePolicies:                  testCase.policies,
+					CheckSuiteOKToTestLabel:             testOKToTestLabel,
+				},
+				requestCheckSuiteFn: func(
+					context.Context,
+					ghlib.App,
+					int64,
+					string,
+					string,
+					string,
+				) error {
+					forwarded = true
+					return nil
+				},
+				getAuthorAssociationFn: func(
+					context.Context,
+					ghlib.App,
+					int64,
+					string,
+					string,
+					string,
+				) (string, error) {
+					return testCase.pusherAssociation, nil
+				},
+				removeLabelFn: func(
+					_ context.Context,
+					_ ghlib.App,
+					_ int64,
+					_ string,
+					_ string,
+					_ int,
+					label string,
+				) error {
+					require.Equal(t, testOKToTestLabel, label)
+					labelRemoved = true
+					return nil
+				},
+			}
+			err := s.checkSuiteForwarding(
+				context.Background(),
+				42,
+				testCase.webhook,
+				nil,
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectForwarded, forwarded)
+			require.Equal(t, testCase.expectLabelRemoved, labelRemoved)
+		})
+	}
+}
+
+func TestCheckSuiteForwardingForComments(t *testing.T) {
+	testCases := []struct {
+		name            string
+		comment         string
+		expectForwarded bool
+	}{
+		{
+			name:            "check command",
+			comment:         "LGTM\n/brig check",
+			expectForwarded: true,
+		},
+		{
+			name:            "run command; mixed case",
+			comment:         "/BRIG Run",
+			expectForwarded: true,
+		},
+		{
+			name:            "parenthesized command",
+			comment:         "Looks safe (/brig run)",
+			expectForwarded: true,
+		},
+		{
+			name:            "backtick-wrapped command",
+			comment:         "Go ahead: `/brig check`",
+			expectForwarded: true,
+		},
+		{
+			name:            "command following another command",
+			comment:         "/brig triage\n/brig check",
+			expectForwarded: true,
+		},
+		{
+			name:    "other command",
+			comment: "/brig triage",
+		},
+		{
+			name:    "no command",
+			comment: "LGTM",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var forwarded bool
+			s := &service{
+				config: ServiceConfig{
+					CheckSuiteAllowedAuthorAssociations: []string{"OWNER"},
+				},
+				requestCheckSuiteFn: func(
+					_ context.Context,
+					_ ghlib.App,
+					_ int64,
+					_ string,
+					_ string,
+					commit string,
+				) error {
+					require.Equal(t, "1234567", commit)
+					forwarded = true
+					return nil
+				},
+			}
+			err := s.checkSuiteForwarding(
+				context.Background(),
+				42,
+				&github.IssueCommentEvent{
+					Action: github.String("created"),
+					Issue: &github.Issue{
+						PullRequestLinks: &github.PullRequestLinks{},
+					},
+					Comment: &github.IssueComment{
+						Body:              github.String(testCase.comment),
+						AuthorAssociation: github.String("OWNER"),
+					},
+				},
+				func() (*github.PullRequest, error) {
+					return &github.PullRequest{
+						Head: &github.PullRequestBranch{
+							SHA: github.String("1234567"),
+						},
+					}, nil
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectForwarded, forwarded)
+		})
+	}
+}
+
+func TestGetCommentPRFn(t *testing.T) {
+	calls := 0
+	s := &service{
+		getPRFromIssueCommentWebhookFn: func(
+			_ context.Context,
+			_ ghlib.App,
+			ice github.IssueCommentEvent,
+		) (*github.PullRequest, error) {
+			calls++
+			return &github.PullRequest{
+				Number: github.Int(ice.GetIssue().GetNumber()),
+			}, nil
+		},
+	}
+	getCommentPR := s.getCommentPRFn(
+		context.Background(),
+		42,
+		&github.IssueCommentEvent{
+			Issue: &github.Issue{
+				Number: github.Int(42),
+			},
+		},
+	)
+	// The PR should not be retrieved until it's needed
+	require.Equal(t, 0, calls)
+	for i := 0; i < 2; i++ {
+		pr, err := getCommentPR()
+		require.NoError(t, err)
+		require.Equal(t, 42, pr.GetNumber())
+	}
+	// The PR should only have been retrieved once
+	require.Equal(t, 1, calls)
+}
+
 func TestIsAllowedAuthorAssociation(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -36,7 +503,8 @@ func TestIsAllowedAuthorAssociation(t *testing.T) {
 			require.Equal(
 				t,
 				testCase.expectedResult,
-				s.isAllowedAuthorAssociation(testCase.association),
+				s.getCheckSuitePolicy("brigadecore/brigade").
+					isAllowedAuthorAssociation(testCase.association),
 			)
 		})
 	}
@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// validAuthorAssociations enumerates all author associations GitHub may report
+// for the author of a PR or comment.
+var validAuthorAssociations = map[string]struct{}{
+	"COLLABORATOR":           {},
+	"CONTRIBUTOR":            {},
+	"FIRST_TIMER":            {},
+	"FIRST_TIME_CONTRIBUTOR": {},
+	"MANNEQUIN":              {},
+	"MEMBER":                 {},
+	"NONE":                   {},
+	"OWNER":                  {},
+}
+
+// CheckSuitePolicy overrides global check suite forwarding configuration for
+// all repositories matching any of the policy's Repos patterns. Any field left
+// unset defers to the corresponding global configuration.
+type CheckSuitePolicy struct {
+	// Name is a human-friendly name for the policy. It is used only for logging
+	// which policy was applied to any check suite forwarding decision.
+	Name string `json:"name"`
+	// Repos enumerates glob patterns that select the repositories to which this
+	// policy applies. A pattern that does not contain a "/" (e.g. "brigadecore")
+	// is matched against a repository's owner (i.e. an org or user). A pattern
+	// that does contain a "/" (e.g. "brigadecore/brigade-*") is matched against a
+	// repository's full name.
+	Repos []string `json:"repos"`
+	// AllowedAuthorAssociations, if specified, overrides the global list of
+	// author associations who are allowed to have their PRs and "/brig check" or
+	// "/brig run" comments trigger the creation of a GitHub CheckSuite.
+	AllowedAuthorAssociations []string `json:"allowedAuthorAssociations,omitempty"` // nolint: lll
+	// ForwardForkPRs, if specified, indicates whether PRs from forks should
+	// trigger the creation of a GitHub CheckSuite at all. Defaults to true.
+	ForwardForkPRs *bool `json:"forwardForkPRs,omitempty"`
+	// CommandsEnabled, if specified, indicates whether "/brig check" or
+	// "/brig run" comments should trigger the creation of a GitHub CheckSuite.
+	// Defaults to true.
+	CommandsEnabled *bool `json:"commandsEnabled,omitempty"`
+	// SkipDraftPRs, if specified, overrides the global setting that indicates
+	// whether draft PRs should be skipped until they are marked ready for
+	// review.
+	SkipDraftPRs *bool `json:"skipDraftPRs,omitempty"`
+}
+
+// Validate returns an error if the CheckSuitePolicy is invalid.
+func (c CheckSuitePolicy) Validate() error {
+	if c.Name == "" {
+		return errors.New("check suite policy has no name")
+	}
+	if len(c.Repos) == 0 {
+		return errors.Errorf(
+			"check suite policy %q does not specify any repos",
+			c.Name,
+		)
+	}
+	if err := validateRepoPatterns(c.Repos); err != nil {
+		return errors.Wrapf(err, "check suite policy %q is invalid", c.Name)
+	}
+	for _, association := range c.AllowedAuthorAssociations {
+		if _, ok := validAuthorAssociations[association]; !ok {
+			return errors.Errorf(
+				"check suite policy %q has invalid author association %q",
+				c.Name,
+				association,
+			)
+		}
+	}
+	return nil
+}
+
+// matches returns a boolean indicating whether the CheckSuitePolicy applies to
+// the repository having the specified full name (i.e. <owner>/<name>).
+func (c CheckSuitePolicy) matches(repoFullName string) bool {
+	return repoMatches(c.Repos, repoFullName)
+}
+
+// validateRepoPatterns returns an error if any of the specified repo patterns
+// is malformed.
+func validateRepoPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid repo pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// repoMatches returns a boolean indicating whether any of the specified repo
+// patterns matches the repository having the specified full name (i.e.
+// <owner>/<name>). A pattern that does not contain a "/" is matched against
+// the repository's owner. A pattern that does contain a "/" is matched against
+// the repository's full name.
+func repoMatches(patterns []string, repoFullName string) bool {
+	owner := strings.SplitN(repoFullName, "/", 2)[0]
+	for _, pattern := range patterns {
+		subject := repoFullName
+		if !strings.Contains(pattern, "/") {
+			subject = owner
+		}
+		// Patterns were validated at startup, so we can ignore the error.
+		if matched, _ := path.Match(pattern, subject); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSuitePolicy represents the effective check suite forwarding policy for a
+// given repository. It is the result of applying any matching CheckSuitePolicy
+// over the global configuration.
+type checkSuitePolicy struct {
+	name                      string
+	allowedAuthorAssociations []string
+	forwardForkPRs            bool
+	commandsEnabled           bool
+	skipDraftPRs              bool
+}
+
+// getCheckSuitePolicy returns the effective check suite forwarding policy for
+// the repository having the specified full name (i.e. <owner>/<name>). The
+// first CheckSuitePolicy that matches the repository is applied over the global
+// configuration. If no CheckSuitePolicy matches, the global configuration
+// applies unmodified.
+func (s *service) getCheckSuitePolicy(repoFullName string) checkSuitePolicy {
+	policy := checkSuitePolicy{
+		name:                      "default",
+		allowedAuthorAssociations: s.config.CheckSuiteAllowedAuthorAssociations,
+		forwardForkPRs:            true,
+		commandsEnabled:           true,
+		skipDraftPRs:              s.config.CheckSuiteSkipDraftPRs,
+	}
+	for _, override := range s.config.CheckSuitePolicies {
+		if !override.matches(repoFullName) {
+			continue
+		}
+		policy.name = override.Name
+		if override.AllowedAuthorAssociations != nil {
+			policy.allowedAuthorAssociations = override.AllowedAuthorAssociations
+		}
+		if override.ForwardForkPRs != nil {
+			policy.forwardForkPRs = *override.ForwardForkPRs
+		}
+		if override.CommandsEnabled != nil {
+			policy.commandsEnabled = *override.CommandsEnabled
+		}
+		if override.SkipDraftPRs != nil {
+			policy.skipDraftPRs = *override.SkipDraftPRs
+		}
+		break
+	}
+	return policy
+}
+
+// isAllowedAuthorAssociation makes a determination whether an author having the
+// specified relationship to a given repository is permitted to have check
+// suites automatically created and executed.
+func (c checkSuitePolicy) isAllowedAuthorAssociation(
+	authorAssociation string,
+) bool {
+	for _, a := range c.allowedAuthorAssociations {
+		if a == authorAssociation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSuitePolicyValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     CheckSuitePolicy
+		assertions func(error)
+	}{
+		{
+			name:   "no name",
+			policy: CheckSuitePolicy{},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has no name")
+			},
+		},
+		{
+			name: "no repos",
+			policy: CheckSuitePolicy{
+				Name: "foo",
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any repos")
+			},
+		},
+		{
+			name: "invalid repo pattern",
+			policy: CheckSuitePolicy{
+				Name:  "foo",
+				Repos: []string{"brigadecore/["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid repo pattern")
+			},
+		},
+		{
+			name: "invalid author association",
+			policy: CheckSuitePolicy{
+				Name:                      "foo",
+				Repos:                     []string{"brigadecore"},
+				AllowedAuthorAssociations: []string{"BOGUS"},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid author association")
+			},
+		},
+		{
+			name: "valid",
+			policy: CheckSuitePolicy{
+				Name:                      "foo",
+				Repos:                     []string{"brigadecore", "krancour/*"},
+				AllowedAuthorAssociations: []string{"OWNER", "CONTRIBUTOR"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(testCase.policy.Validate())
+		})
+	}
+}
+
+func TestCheckSuitePolicyMatches(t *testing.T) {
+	testCases := []struct {
+		name         string
+		repos        []string
+		repoFullName string
+		matches      bool
+	}{
+		{
+			name:         "org pattern matches",
+			repos:        []string{"brigadecore"},
+			repoFullName: "brigadecore/brigade",
+			matches:      true,
+		},
+		{
+			name:         "org pattern does not match",
+			repos:        []string{"brigadecore"},
+			repoFullName: "krancour/brigade",
+			matches:      false,
+		},
+		{
+			name:         "repo glob matches",
+			repos:        []string{"brigadecore/brigade-*"},
+			repoFullName: "brigadecore/brigade-github-gateway",
+			matches:      true,
+		},
+		{
+			name:         "repo glob does not match",
+			repos:        []string{"brigadecore/brigade-*"},
+			repoFullName: "brigadecore/brigade",
+			matches:      false,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			policy := CheckSuitePolicy{Repos: testCase.repos}
+			require.Equal(
+				t,
+				testCase.matches,
+				policy.matches(testCase.repoFullName),
+			)
+		})
+	}
+}
+
+func TestGetCheckSuitePolicy(t *testing.T) {
+	s := &service{
+		config: ServiceConfig{
+			CheckSuiteAllowedAuthorAssociations: []string{"OWNER", "CONTRIBUTOR"},
+			CheckSuitePolicies: []CheckSuitePolicy{
+				{
+					Name:                      "open-source",
+					Repos:                     []string{"brigadecore/brigade-*"},
+					AllowedAuthorAssociations: []string{"OWNER"},
+					CommandsEnabled:           boolPtr(false),
+				},
+				{
+					Name:           "brigadecore",
+					Repos:          []string{"brigadecore"},
+					ForwardForkPRs: boolPtr(false),
+					SkipDraftPRs:   boolPtr(true),
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		name           string
+		repoFullName   string
+		expectedPolicy checkSuitePolicy
+	}{
+		{
+			name:         "no policy matches",
+			repoFullName: "krancour/brigade",
+			expectedPolicy: checkSuitePolicy{
+				name:                      "default",
+				allowedAuthorAssociations: []string{"OWNER", "CONTRIBUTOR"},
+				forwardForkPRs:            true,
+				commandsEnabled:           true,
+			},
+		},
+		{
+			name:         "first matching policy wins",
+			repoFullName: "brigadecore/brigade-github-gateway",
+			expectedPolicy: checkSuitePolicy{
+				name:                      "open-source",
+				allowedAuthorAssociations: []string{"OWNER"},
+				forwardForkPRs:            true,
+				commandsEnabled:           false,
+			},
+		},
+		{
+			name:         "org policy matches",
+			repoFullName: "brigadecore/brigade",
+			expectedPolicy: checkSuitePolicy{
+				name:                      "brigadecore",
+				allowedAuthorAssociations: []string{"OWNER", "CONTRIBUTOR"},
+				forwardForkPRs:            false,
+				commandsEnabled:           true,
+				skipDraftPRs:              true,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedPolicy,
+				s.getCheckSuitePolicy(testCase.repoFullName),
+			)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
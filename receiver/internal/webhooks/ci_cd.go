@@ -1,45 +1,328 @@
 package webhooks
 
 import (
+	"log"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/brigadecore/brigade/sdk/v3"
 	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
 )
 
-func (s *service) getCICDEvent(event sdk.Event) *sdk.Event {
-	ciCDEvent := &event
-	switch event.Type {
+// installationGetter is implemented by all webhook types having an
+// installation property.
+type installationGetter interface {
+	GetInstallation() *github.Installation
+}
+
+// CICDMapping maps events matching its conditions to an additional event of
+// particular importance to CI/CD (e.g. cd:pipeline_requested) that is emitted
+// alongside them.
+type CICDMapping struct {
+	// Name is a human-friendly name for the mapping. It is used only for logging
+	// which mapping was applied to any event.
+	Name string `json:"name"`
+	// EventTypes enumerates glob patterns that select the types of events (e.g.
+	// "push" or "pull_request:labeled") to which this mapping applies.
+	EventTypes []string `json:"eventTypes"`
+	// Type is the type of the event to be emitted. It must begin with "ci:" or
+	// "cd:".
+	Type string `json:"type"`
+	// Repos optionally enumerates glob patterns that select the repositories to
+	// which this mapping applies. A pattern that does not contain a "/" (e.g.
+	// "brigadecore") is matched against a repository's owner. A pattern that
+	// does contain a "/" (e.g. "brigadecore/brigade-*") is matched against a
+	// repository's full name. If unspecified, the mapping applies to all
+	// repositories.
+	Repos []string `json:"repos,omitempty"`
+	// Branches optionally enumerates glob patterns that select the branches
+	// (e.g. "main" or "release/*") to which this mapping applies. For events
+	// pertaining to a PR, this is the PR's head branch.
+	Branches []string `json:"branches,omitempty"`
+	// Tags optionally enumerates glob patterns that select the tags (e.g.
+	// "v*") to which this mapping applies.
+	Tags []string `json:"tags,omitempty"`
+	// Labels optionally specifies labels that an event must have for this
+	// mapping to apply to it. Rich labels (e.g. "label" for a PR's labeled
+	// action) are taken into account even if they are not enabled.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tracking indicates whether the results of any pipeline handling the
+	// emitted event should be reported upstream to GitHub, just as they are for
+	// check suites. This requires the event to reference a specific commit.
+	Tracking bool `json:"tracking,omitempty"`
+
+	// enabledFn optionally imposes additional conditions that cannot be
+	// expressed in configuration. It is used only by built-in mappings.
+	enabledFn func(s *service, event sdk.Event) bool
+	// labelsFn optionally returns additional labels to be applied to the
+	// emitted event. It is used only by built-in mappings.
+	labelsFn func(event sdk.Event) map[string]string
+}
+
+// Validate returns an error if the CICDMapping is invalid.
+func (c CICDMapping) Validate() error {
+	if c.Name == "" {
+		return errors.New("CI/CD mapping has no name")
+	}
+	if len(c.EventTypes) == 0 {
+		return errors.Errorf(
+			"CI/CD mapping %q does not specify any event types",
+			c.Name,
+		)
+	}
+	if !strings.HasPrefix(c.Type, "ci:") && !strings.HasPrefix(c.Type, "cd:") {
+		return errors.Errorf(
+			`CI/CD mapping %q has invalid type %q; type must begin with "ci:" `+
+				`or "cd:"`,
+			c.Name,
+			c.Type,
+		)
+	}
+	if err := validateRepoPatterns(c.Repos); err != nil {
+		return errors.Wrapf(err, "CI/CD mapping %q is invalid", c.Name)
+	}
+	for _, patterns := range [][]string{c.EventTypes, c.Branches, c.Tags} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrapf(
+					err,
+					"CI/CD mapping %q has invalid pattern %q",
+					c.Name,
+					pattern,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// matches returns a boolean indicating whether the CICDMapping applies to the
+// specified event. The event's labels are not consulted directly. The
+// specified labels, which should include any rich labels, are consulted
+// instead.
+func (c CICDMapping) matches(
+	s *service,
+	event sdk.Event,
+	labels map[string]string,
+) bool {
+	if !globMatches(c.EventTypes, event.Type) {
+		return false
+	}
+	if len(c.Repos) > 0 && !repoMatches(c.Repos, event.Qualifiers["repo"]) {
+		return false
+	}
+	if len(c.Branches) > 0 &&
+		(labels["branch"] == "" || !globMatches(c.Branches, labels["branch"])) {
+		return false
+	}
+	if len(c.Tags) > 0 &&
+		(labels["tag"] == "" || !globMatches(c.Tags, labels["tag"])) {
+		return false
+	}
+	for key, value := range c.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return c.enabledFn == nil || c.enabledFn(s, event)
+}
+
+// builtInCICDMappings enumerates the mappings that are always in effect. They
+// are consulted before any configured mappings.
+var builtInCICDMappings = []CICDMapping{
 	// Where check_suite:requested and check_suite:rerequested are concerned, it's
 	// rare that any subscriber needs to differentiate between the two, so to
 	// simplify matters for most subscribers, we collapse those two cases into a
 	// ci:pipeline_requested event and we emit that in addition to the original
 	// check_suite:requested or check_suite:rerequested event.
-	case "check_suite:requested", "check_suite:rerequested":
-		ciCDEvent.Type = "ci:pipeline_requested"
+	{
+		Name:       "check-suites",
+		EventTypes: []string{"check_suite:requested", "check_suite:rerequested"},
+		Type:       "ci:pipeline_requested",
+	},
+	// Similarly, when a merge queue requests checks for a merge group, what's
+	// really being requested is that the CI pipeline run against the merge
+	// group's temporary branch.
+	{
+		Name:       "merge-groups",
+		EventTypes: []string{"merge_group:checks_requested"},
+		Type:       "ci:pipeline_requested",
+	},
 	// For consistency with the above, we emit a ci:job_requested event in
 	// addition to the original check_run:rerequested event.
-	case "check_run:rerequested":
-		ciCDEvent.Type = "ci:job_requested"
-		// We should always be able to parse this. If this were not parsable, we
-		// would have encountered an error long before this function was called.
-		// nolint: errcheck
-		webhook, _ := github.ParseWebHook("check_run", []byte(event.Payload))
-		// If we're already emitting a check_run:rerequested, the original webhook
-		// MUST have been a *github.CheckRunEvent, so we can safely assume this
-		// type assertion always succeeds.
-		// nolint: forcetypeassert
-		checkRunWebHook := webhook.(*github.CheckRunEvent)
-		// Job names from this gateway are always of the form <project:job>
-		qualifiedJobName := checkRunWebHook.GetCheckRun().GetName()
-		jobNameTokens := strings.SplitN(qualifiedJobName, ":", 2)
-		ciCDEvent.Labels["job"] = jobNameTokens[1]
+	{
+		Name:       "check-runs",
+		EventTypes: []string{"check_run:rerequested"},
+		Type:       "ci:job_requested",
+		labelsFn:   getCheckRunJobLabels,
+	},
+	// A GitHub Actions workflow run that completed successfully may also be
+	// configured to trigger a CI pipeline in Brigade.
+	{
+		Name:       "workflow-runs",
+		EventTypes: []string{"workflow_run:completed"},
+		Type:       "ci:pipeline_requested",
+		enabledFn:  (*service).isWorkflowRunTrigger,
+	},
 	// For consistency with the above, we emit a cd:pipeline_requested event in
 	// addition to the original release:published event.
-	case "release:published":
-		ciCDEvent.Type = "cd:pipeline_requested"
-	default:
+	{
+		Name:       "releases",
+		EventTypes: []string{"release:published"},
+		Type:       "cd:pipeline_requested",
+	},
+	// Optionally, the same applies to packages, such as container images, that
+	// have been published to GitHub Packages.
+	{
+		Name:       "packages",
+		EventTypes: []string{"package:published", "registry_package:published"},
+		Type:       "cd:pipeline_requested",
+		enabledFn: func(s *service, _ sdk.Event) bool {
+			return s.config.PackageCDEnabled
+		},
+	},
+}
+
+// getCICDEvent returns an event of particular importance to CI/CD that should
+// be emitted in addition to the specified event, as determined by the first
+// built-in or configured CICDMapping that applies to it. If no mapping applies,
+// nil is returned.
+func (s *service) getCICDEvent(
+	webhook interface{},
+	event sdk.Event,
+) *sdk.Event {
+	mapping := s.getCICDMapping(webhook, event)
+	if mapping == nil {
+		return nil
+	}
+	// If the head commit message requested that CI be skipped, we honor that.
+	// Markers in commit messages have no bearing on CD, however.
+	if event.Labels["skipped"] == "true" &&
+		strings.HasPrefix(mapping.Type, "ci:") {
 		return nil
 	}
+	ciCDEvent := &event
+	ciCDEvent.Type = mapping.Type
+	if mapping.labelsFn != nil {
+		ciCDEvent.Labels = copyLabels(ciCDEvent.Labels, mapping.labelsFn(event))
+	}
+	// Events that are already being tracked, such as those resulting from check
+	// suites, retain their existing source state.
+	if mapping.Tracking && ciCDEvent.SourceState == nil {
+		if ciCDEvent.SourceState =
+			getTrackingSourceState(webhook, event); ciCDEvent.SourceState == nil {
+			log.Printf(
+				"CI/CD mapping %q enables tracking, but event %q does not reference "+
+					"a specific commit in a repository where the GitHub App is "+
+					"installed; the results of its pipeline will not be reported",
+				mapping.Name,
+				event.Type,
+			)
+		}
+	}
 	return ciCDEvent
 }
+
+// getCICDMapping returns the first built-in or configured CICDMapping that
+// applies to the specified event. If no mapping does, nil is returned.
+func (s *service) getCICDMapping(
+	webhook interface{},
+	event sdk.Event,
+) *CICDMapping {
+	// Rich labels are taken into account even if they aren't enabled, so that
+	// mappings can impose conditions on branches, tags, etc. Labels already set
+	// take precedence.
+	labels := copyLabels(getRichLabels(webhook, ""), event.Labels)
+	for _, mappings := range [][]CICDMapping{
+		builtInCICDMappings,
+		s.config.CICDMappings,
+	} {
+		for i, mapping := range mappings {
+			if mapping.matches(s, event, labels) {
+				return &mappings[i]
+			}
+		}
+	}
+	return nil
+}
+
+// getCheckRunJobLabels returns a label conveying the name of the job that a
+// check_run:rerequested event pertains to.
+func getCheckRunJobLabels(event sdk.Event) map[string]string {
+	// We should always be able to parse this. If this were not parsable, we
+	// would have encountered an error long before this function was called.
+	// nolint: errcheck
+	webhook, _ := github.ParseWebHook("check_run", []byte(event.Payload))
+	// If we're handling a check_run:rerequested event, the original webhook MUST
+	// have been a *github.CheckRunEvent, so we can safely assume this type
+	// assertion always succeeds.
+	// nolint: forcetypeassert
+	checkRunWebHook := webhook.(*github.CheckRunEvent)
+	// Job names from this gateway are always of the form <project:job>
+	qualifiedJobName := checkRunWebHook.GetCheckRun().GetName()
+	jobNameTokens := strings.SplitN(qualifiedJobName, ":", 2)
+	return map[string]string{
+		"job": jobNameTokens[1],
+	}
+}
+
+// getTrackingSourceState returns source state that enables the results of any
+// pipeline handling the specified event to be reported upstream to GitHub. If
+// the event does not reference a specific commit in a repository where the
+// GitHub App is installed, nil is returned.
+func getTrackingSourceState(
+	webhook interface{},
+	event sdk.Event,
+) *sdk.SourceState {
+	w, ok := webhook.(installationGetter)
+	if !ok || w.GetInstallation().GetID() == 0 {
+		return nil
+	}
+	repoTokens := strings.SplitN(event.Qualifiers["repo"], "/", 2)
+	if len(repoTokens) != 2 || event.Git == nil || event.Git.Commit == "" {
+		return nil
+	}
+	return &sdk.SourceState{
+		State: map[string]string{
+			"tracking": "true",
+			"installationID": strconv.FormatInt(
+				w.GetInstallation().GetID(),
+				10,
+			),
+			"owner":   repoTokens[0],
+			"repo":    repoTokens[1],
+			"headSHA": event.Git.Commit,
+		},
+	}
+}
+
+// getWorkflowLabels returns labels conveying the name of a GitHub Actions
+// workflow and, if applicable, the conclusion of a workflow run or job.
+func getWorkflowLabels(workflow string, conclusion string) map[string]string {
+	labels := map[string]string{}
+	if workflow != "" {
+		labels["workflow"] = workflow
+	}
+	if conclusion != "" {
+		labels["conclusion"] = conclusion
+	}
+	return labels
+}
+
+// isWorkflowRunTrigger returns a boolean indicating whether the specified
+// workflow_run event represents the successful completion of a GitHub Actions
+// workflow that is configured to trigger a CI pipeline in Brigade.
+func (s *service) isWorkflowRunTrigger(event sdk.Event) bool {
+	if event.Type != "workflow_run:completed" ||
+		event.Labels["conclusion"] != "success" {
+		return false
+	}
+	for _, workflow := range s.config.WorkflowRunTriggers {
+		if workflow == event.Labels["workflow"] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,366 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCICDMappingValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mapping    CICDMapping
+		assertions func(error)
+	}{
+		{
+			name:    "no name",
+			mapping: CICDMapping{},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has no name")
+			},
+		},
+		{
+			name: "no event types",
+			mapping: CICDMapping{
+				Name: "foo",
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any event types")
+			},
+		},
+		{
+			name: "invalid type",
+			mapping: CICDMapping{
+				Name:       "foo",
+				EventTypes: []string{"push"},
+				Type:       "push:main",
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has invalid type")
+			},
+		},
+		{
+			name: "invalid repo pattern",
+			mapping: CICDMapping{
+				Name:       "foo",
+				EventTypes: []string{"push"},
+				Type:       "cd:pipeline_requested",
+				Repos:      []string{"brigadecore/["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid repo pattern")
+			},
+		},
+		{
+			name: "invalid branch pattern",
+			mapping: CICDMapping{
+				Name:       "foo",
+				EventTypes: []string{"push"},
+				Type:       "cd:pipeline_requested",
+				Branches:   []string{"["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid pattern")
+			},
+		},
+		{
+			name: "valid",
+			mapping: CICDMapping{
+				Name:       "foo",
+				EventTypes: []string{"push"},
+				Type:       "cd:pipeline_requested",
+				Branches:   []string{"main"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(testCase.mapping.Validate())
+		})
+	}
+}
+
+func TestGetCICDEvent(t *testing.T) {
+	testPushWebhook := &github.PushEvent{
+		Ref: github.String("refs/heads/main"),
+		Installation: &github.Installation{
+			ID: github.Int64(42),
+		},
+	}
+	testPushEvent := sdk.Event{
+		Type: "push",
+		Qualifiers: map[string]string{
+			"repo": "brigadecore/brigade-github-gateway",
+		},
+		Git: &sdk.GitDetails{
+			Commit: "1234567",
+		},
+	}
+	testPRWebhook := &github.PullRequestEvent{
+		Action: github.String("labeled"),
+		Label: &github.Label{
+			Name: github.String("deploy-preview"),
+		},
+	}
+	testPREvent := sdk.Event{
+		Type: "pull_request:labeled",
+		Qualifiers: map[string]string{
+			"repo": "brigadecore/brigade-github-gateway",
+		},
+	}
+	testMappings := []CICDMapping{
+		{
+			Name:       "deploy-main",
+			EventTypes: []string{"push"},
+			Type:       "cd:pipeline_requested",
+			Repos:      []string{"brigadecore"},
+			Branches:   []string{"main"},
+			Tracking:   true,
+		},
+		{
+			Name:       "deploy-previews",
+			EventTypes: []string{"pull_request:labeled"},
+			Type:       "cd:pipeline_requested",
+			Labels: map[string]string{
+				"label": "deploy-preview",
+			},
+		},
+	}
+	testCases := []struct {
+		name       string
+		service    *service
+		webhook    interface{}
+		event      sdk.Event
+		assertions func(*sdk.Event)
+	}{
+		{
+			name:    "built-in mapping",
+			service: &service{},
+			webhook: &github.ReleaseEvent{},
+			event: sdk.Event{
+				Type: "release:published",
+			},
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+			},
+		},
+		{
+			name:    "built-in mapping not enabled",
+			service: &service{},
+			webhook: &packageEvent{},
+			event: sdk.Event{
+				Type: "package:published",
+			},
+			assertions: func(event *sdk.Event) {
+				require.Nil(t, event)
+			},
+		},
+		{
+			name:    "no mapping applies",
+			service: &service{},
+			webhook: testPushWebhook,
+			event:   testPushEvent,
+			assertions: func(event *sdk.Event) {
+				require.Nil(t, event)
+			},
+		},
+		{
+			name: "configured mapping applies; tracking enabled",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: testMappings,
+				},
+			},
+			webhook: testPushWebhook,
+			event:   testPushEvent,
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.Equal(
+					t,
+					&sdk.SourceState{
+						State: map[string]string{
+							"tracking":       "true",
+							"installationID": "42",
+							"owner":          "brigadecore",
+							"repo":           "brigade-github-gateway",
+							"headSHA":        "1234567",
+						},
+					},
+					event.SourceState,
+				)
+			},
+		},
+		{
+			name: "configured mapping does not apply to branch",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: testMappings,
+				},
+			},
+			webhook: &github.PushEvent{
+				Ref: github.String("refs/heads/feature"),
+			},
+			event: testPushEvent,
+			assertions: func(event *sdk.Event) {
+				require.Nil(t, event)
+			},
+		},
+		{
+			name: "configured mapping applies to label",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: testMappings,
+				},
+			},
+			webhook: testPRWebhook,
+			event:   testPREvent,
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.Nil(t, event.SourceState)
+			},
+		},
+		{
+			name: "configured mapping applies to alert; tracking enabled",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: []CICDMapping{
+						{
+							Name:       "triage-alerts",
+							EventTypes: []string{"code_scanning_alert:*"},
+							Type:       "ci:pipeline_requested",
+							Tracking:   true,
+						},
+					},
+				},
+			},
+			webhook: &codeScanningAlertEvent{
+				Action: github.String("created"),
+				Installation: &github.Installation{
+					ID: github.Int64(42),
+				},
+			},
+			event: sdk.Event{
+				Type:       "code_scanning_alert:created",
+				Qualifiers: testPushEvent.Qualifiers,
+				Git:        testPushEvent.Git,
+			},
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "ci:pipeline_requested", event.Type)
+				require.NotNil(t, event.SourceState)
+				require.Equal(
+					t,
+					"42",
+					event.SourceState.State["installationID"],
+				)
+			},
+		},
+		{
+			name: "configured mapping applies to package; tracking enabled",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: []CICDMapping{
+						{
+							Name:       "publish",
+							EventTypes: []string{"package:published"},
+							Type:       "cd:pipeline_requested",
+							Tracking:   true,
+						},
+					},
+				},
+			},
+			webhook: &packageEvent{
+				Action: github.String("published"),
+				Installation: &github.Installation{
+					ID: github.Int64(42),
+				},
+			},
+			event: sdk.Event{
+				Type:       "package:published",
+				Qualifiers: testPushEvent.Qualifiers,
+				Git: &sdk.GitDetails{
+					Commit: "1234567",
+				},
+			},
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.NotNil(t, event.SourceState)
+				require.Equal(t, "1234567", event.SourceState.State["headSHA"])
+			},
+		},
+		{
+			name:    "CI skipped",
+			service: &service{},
+			webhook: &github.CheckSuiteEvent{
+				Action:     github.String("requested"),
+				CheckSuite: &github.CheckSuite{},
+			},
+			event: sdk.Event{
+				Type: "check_suite:requested",
+				Labels: map[string]string{
+					"skipped": "true",
+				},
+			},
+			assertions: func(event *sdk.Event) {
+				require.Nil(t, event)
+			},
+		},
+		{
+			name: "CI skipped; configured CD mapping applies",
+			service: &service{
+				config: ServiceConfig{
+					CICDMappings: testMappings,
+				},
+			},
+			webhook: testPushWebhook,
+			event: sdk.Event{
+				Type:       testPushEvent.Type,
+				Qualifiers: testPushEvent.Qualifiers,
+				Git:        testPushEvent.Git,
+				Labels: map[string]string{
+					"skipped": "true",
+				},
+			},
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+			},
+		},
+		{
+			name:    "CI skipped; release",
+			service: &service{},
+			webhook: &github.ReleaseEvent{},
+			event: sdk.Event{
+				Type: "release:published",
+				Labels: map[string]string{
+					"skipped": "true",
+				},
+			},
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				testCase.service.getCICDEvent(testCase.webhook, testCase.event),
+			)
+		})
+	}
+}
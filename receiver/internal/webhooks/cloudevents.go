@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/pkg/errors"
+)
+
+// cloudEventsSpecVersion is the version of the CloudEvents specification that
+// envelopes produced by this gateway conform to.
+const cloudEventsSpecVersion = "1.0"
+
+// cloudEvent represents a CloudEvents envelope, as defined by the CloudEvents
+// JSON event format specification.
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	DataContentType string `json:"datacontenttype"`
+	// GitHubDelivery is an extension attribute conveying the GUID GitHub
+	// assigned to the webhook delivery, since a single delivery may result in
+	// more than one event and each must have a distinct ID.
+	GitHubDelivery string          `json:"githubdelivery,omitempty"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// toCloudEvents replaces the payload of each of the specified events with a
+// CloudEvents envelope containing the original payload as its data. The
+// events' types, qualifiers, and labels are not modified, so subscriptions are
+// unaffected. The specified deliveryID is used as the ID of the first event.
+// Since a single webhook delivery may result in more than one event and the
+// CloudEvents specification requires each to have a distinct ID, the IDs of
+// any subsequent events are suffixed with the event's index.
+func toCloudEvents(
+	events []sdk.Event,
+	deliveryID string,
+) ([]sdk.Event, error) {
+	for i, event := range events {
+		id := deliveryID
+		if id == "" {
+			// The delivery ID should always be known, but if it isn't, we derive a
+			// stable ID from the payload instead.
+			sum := sha256.Sum256([]byte(event.Payload))
+			id = hex.EncodeToString(sum[:])
+		}
+		if i > 0 {
+			id = fmt.Sprintf("%s-%d", id, i)
+		}
+		envelope := cloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              id,
+			Source:          getCloudEventSource(event),
+			Type:            event.Type,
+			DataContentType: "application/json",
+			GitHubDelivery:  deliveryID,
+			Data:            json.RawMessage(event.Payload),
+		}
+		if event.Git != nil {
+			// For PRs, this is of the form refs/pull/<number>/head.
+			envelope.Subject = event.Git.Ref
+		}
+		if envelope.Subject == "" {
+			// For deleted refs, event.Git is deliberately left empty, but the ref is
+			// conveyed by a label.
+			envelope.Subject = event.Labels["ref"]
+		}
+		envelopeBytes, err := json.Marshal(envelope)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error marshaling CloudEvents envelope for event %q",
+				event.Type,
+			)
+		}
+		events[i].Payload = string(envelopeBytes)
+	}
+	return events, nil
+}
+
+// getCloudEventSource returns the URL of the repository that the specified
+// event's payload pertains to. If the payload does not pertain to a
+// repository, the event's own source is returned instead.
+func getCloudEventSource(event sdk.Event) string {
+	if repoURL := getRepoURL(event.Payload); repoURL != "" {
+		return repoURL
+	}
+	return event.Source
+}
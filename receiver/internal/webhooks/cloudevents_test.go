@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToCloudEvents(t *testing.T) {
+	const testPayload = `{"repository":{"html_url":"https://github.com/brigadecore/brigade-github-gateway"}}` // nolint: lll
+	testCases := []struct {
+		name       string
+		events     []sdk.Event
+		deliveryID string
+		assertions func([]sdk.Event, error)
+	}{
+		{
+			name: "single event",
+			events: []sdk.Event{
+				{
+					Source:  "brigade.sh/github",
+					Type:    "push",
+					Payload: testPayload,
+					Git: &sdk.GitDetails{
+						Ref: "refs/heads/main",
+					},
+				},
+			},
+			deliveryID: "abc",
+			assertions: func(events []sdk.Event, err error) {
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				envelope := cloudEvent{}
+				err = json.Unmarshal([]byte(events[0].Payload), &envelope)
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					cloudEvent{
+						SpecVersion:     "1.0",
+						ID:              "abc",
+						Source:          "https://github.com/brigadecore/brigade-github-gateway", // nolint: lll
+						Type:            "push",
+						Subject:         "refs/heads/main",
+						DataContentType: "application/json",
+						GitHubDelivery:  "abc",
+						Data:            json.RawMessage(testPayload),
+					},
+					envelope,
+				)
+				// Nothing else about the event should have changed
+				require.Equal(t, "push", events[0].Type)
+			},
+		},
+		{
+			name: "multiple events; no repository",
+			events: []sdk.Event{
+				{
+					Source:  "brigade.sh/github",
+					Type:    "organization:member_added",
+					Payload: "{}",
+				},
+				{
+					Source:  "brigade.sh/github",
+					Type:    "delete:branch",
+					Payload: "{}",
+					Labels: map[string]string{
+						"ref": "refs/heads/feature",
+					},
+				},
+			},
+			deliveryID: "abc",
+			assertions: func(events []sdk.Event, err error) {
+				require.NoError(t, err)
+				require.Len(t, events, 2)
+				envelope := cloudEvent{}
+				err = json.Unmarshal([]byte(events[0].Payload), &envelope)
+				require.NoError(t, err)
+				require.Equal(t, "abc", envelope.ID)
+				require.Equal(t, "brigade.sh/github", envelope.Source)
+				require.Empty(t, envelope.Subject)
+				err = json.Unmarshal([]byte(events[1].Payload), &envelope)
+				require.NoError(t, err)
+				require.Equal(t, "abc-1", envelope.ID)
+				require.Equal(t, "refs/heads/feature", envelope.Subject)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				toCloudEvents(testCase.events, testCase.deliveryID),
+			)
+		})
+	}
+}
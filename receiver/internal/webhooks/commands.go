@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// brigCommandRegex matches a "/brig <command>" command (e.g. "/brig check")
+// anywhere in a comment, case-insensitively.
+var brigCommandRegex = regexp.MustCompile(
+	`(?i)(?:^|\s)/brig\s+([a-z][a-z0-9_-]*)`,
+)
+
+// parseBrigCommand returns the first "/brig" command found in the specified
+// comment body, in lowercase and without the "/brig" prefix (e.g. "check"). If
+// no command is found, an empty string is returned.
+func parseBrigCommand(body string) string {
+	submatches := brigCommandRegex.FindStringSubmatch(body)
+	if len(submatches) != 2 {
+		return ""
+	}
+	return strings.ToLower(submatches[1])
+}
+
+// getBrigCommand returns the first "/brig" command found in the specified
+// comment body, in lowercase and without the "/brig" prefix (e.g. "check"). If
+// no command is found, or if the effective check suite policy for the
+// repository having the specified full name does not permit the comment's
+// author to issue commands, an empty string is returned.
+func (s *service) getBrigCommand(
+	repoFullName string,
+	authorAssociation string,
+	body string,
+) string {
+	command := parseBrigCommand(body)
+	if command == "" {
+		return ""
+	}
+	policy := s.getCheckSuitePolicy(repoFullName)
+	if !policy.commandsEnabled ||
+		!policy.isAllowedAuthorAssociation(authorAssociation) {
+		log.Printf(
+			"ignoring %q command in %s comment; policy %q does not permit it",
+			command,
+			repoFullName,
+			policy.name,
+		)
+		return ""
+	}
+	return command
+}
+
+// getCommandLabels returns a command label conveying the "/brig" command, if
+// any, found in the specified comment body, subject to the same restrictions
+// as getBrigCommand. If there is no such command, an empty map is returned.
+func (s *service) getCommandLabels(
+	repoFullName string,
+	authorAssociation string,
+	body string,
+) map[string]string {
+	labels := map[string]string{}
+	if command :=
+		s.getBrigCommand(repoFullName, authorAssociation, body); command != "" {
+		labels["command"] = command
+	}
+	return labels
+}
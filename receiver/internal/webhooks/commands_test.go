@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBrigCommand(t *testing.T) {
+	const testRepo = "brigadecore/brigade-github-gateway"
+	testCases := []struct {
+		name              string
+		config            ServiceConfig
+		authorAssociation string
+		body              string
+		expectedCommand   string
+	}{
+		{
+			name: "no command",
+			config: ServiceConfig{
+				CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+			},
+			authorAssociation: "MEMBER",
+			body:              "How do I configure this?",
+			expectedCommand:   "",
+		},
+		{
+			name: "command from allowed author",
+			config: ServiceConfig{
+				CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+			},
+			authorAssociation: "MEMBER",
+			body:              "Looks like a bug.\n/BRIG Triage please",
+			expectedCommand:   "triage",
+		},
+		{
+			name: "command from disallowed author",
+			config: ServiceConfig{
+				CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+			},
+			authorAssociation: "NONE",
+			body:              "/brig triage",
+			expectedCommand:   "",
+		},
+		{
+			name: "commands disabled by policy",
+			config: ServiceConfig{
+				CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+				CheckSuitePolicies: []CheckSuitePolicy{
+					{
+						Name:            "no-commands",
+						Repos:           []string{"brigadecore"},
+						CommandsEnabled: new(bool),
+					},
+				},
+			},
+			authorAssociation: "MEMBER",
+			body:              "/brig triage",
+			expectedCommand:   "",
+		},
+		{
+			name: "not a command",
+			config: ServiceConfig{
+				CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+			},
+			authorAssociation: "MEMBER",
+			body:              "See https://example.com/brig triage",
+			expectedCommand:   "",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: testCase.config,
+			}
+			require.Equal(
+				t,
+				testCase.expectedCommand,
+				s.getBrigCommand(
+					testRepo,
+					testCase.authorAssociation,
+					testCase.body,
+				),
+			)
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+)
+
+// ComponentMapping maps paths within a repository to a named component. This
+// is useful for monorepos containing many components, each of which may be
+// built, tested, or deployed by its own Brigade project. Events pertaining to
+// changed paths are labeled with the affected components so that such projects
+// can subscribe only to events affecting their own component.
+type ComponentMapping struct {
+	// Component is the name of the component.
+	Component string `json:"component"`
+	// Repos optionally enumerates glob patterns that select the repositories to
+	// which this mapping applies. A pattern that does not contain a "/" (e.g.
+	// "brigadecore") is matched against a repository's owner (i.e. an org or
+	// user). A pattern that does contain a "/" (e.g. "brigadecore/brigade-*") is
+	// matched against a repository's full name. If no patterns are specified, the
+	// mapping applies to all repositories.
+	Repos []string `json:"repos,omitempty"`
+	// Paths enumerates glob patterns that select the paths, relative to the root
+	// of the repository, that belong to the component. In addition to the syntax
+	// supported by path.Match, a "**" path segment matches zero or more path
+	// segments. e.g. "services/api/**" matches all paths beneath services/api.
+	Paths []string `json:"paths"`
+}
+
+// Validate returns an error if the ComponentMapping is invalid.
+func (c ComponentMapping) Validate() error {
+	if c.Component == "" {
+		return errors.New("component mapping has no component name")
+	}
+	if err := validateRepoPatterns(c.Repos); err != nil {
+		return errors.Wrapf(
+			err,
+			"component mapping for component %q is invalid",
+			c.Component,
+		)
+	}
+	if len(c.Paths) == 0 {
+		return errors.Errorf(
+			"component mapping for component %q does not specify any paths",
+			c.Component,
+		)
+	}
+	for _, pattern := range c.Paths {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return errors.Wrapf(
+					err,
+					"component mapping for component %q has invalid path pattern %q",
+					c.Component,
+					pattern,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// appliesTo returns a boolean indicating whether the ComponentMapping applies
+// to the repository having the specified full name (i.e. <owner>/<name>).
+func (c ComponentMapping) appliesTo(repoFullName string) bool {
+	return len(c.Repos) == 0 || repoMatches(c.Repos, repoFullName)
+}
+
+// matches returns a boolean indicating whether the specified path belongs to
+// the component.
+func (c ComponentMapping) matches(filePath string) bool {
+	for _, pattern := range c.Paths {
+		if matchPath(pattern, filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns a boolean indicating whether the specified path matches the
+// specified glob pattern. In addition to the syntax supported by path.Match, a
+// "**" path segment in the pattern matches zero or more path segments.
+func matchPath(pattern string, filePath string) bool {
+	return matchPathSegments(
+		strings.Split(pattern, "/"),
+		strings.Split(strings.TrimPrefix(filePath, "/"), "/"),
+	)
+}
+
+func matchPathSegments(patternSegments []string, pathSegments []string) bool {
+	for len(patternSegments) > 0 {
+		if patternSegments[0] == "**" {
+			patternSegments = patternSegments[1:]
+			if len(patternSegments) == 0 {
+				return true
+			}
+			for i := range pathSegments {
+				if matchPathSegments(patternSegments, pathSegments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(pathSegments) == 0 {
+			return false
+		}
+		// Patterns were validated at startup, so we can ignore the error.
+		if matched, _ := path.Match(patternSegments[0], pathSegments[0]); !matched {
+			return false
+		}
+		patternSegments = patternSegments[1:]
+		pathSegments = pathSegments[1:]
+	}
+	return len(pathSegments) == 0
+}
+
+// getComponentMappings returns all ComponentMappings that apply to the
+// repository the specified webhook pertains to. Component mappings only apply
+// to push webhooks and to pull_request webhooks whose action signals new or
+// updated changes. For all other webhooks, nil is returned. If nil is returned,
+// events resulting from the webhook should not be modified in any way.
+func (s *service) getComponentMappings(
+	webhook interface{},
+) []ComponentMapping {
+	var repoFullName string
+	switch w := webhook.(type) {
+	case *github.PushEvent:
+		repoFullName = w.GetRepo().GetFullName()
+	case *github.PullRequestEvent:
+		if !isPRChangesAction(w.GetAction()) {
+			return nil
+		}
+		repoFullName = w.GetRepo().GetFullName()
+	default:
+		return nil
+	}
+	var mappings []ComponentMapping
+	for _, mapping := range s.config.ComponentMappings {
+		if mapping.appliesTo(repoFullName) {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+// getAffectedComponents determines which of the components described by the
+// specified ComponentMappings, if any, are affected by changes to the specified
+// paths.
+func getAffectedComponents(
+	mappings []ComponentMapping,
+	changedPaths []string,
+) []string {
+	components := []string{}
+	for _, mapping := range mappings {
+		for _, changedPath := range changedPaths {
+			if mapping.matches(changedPath) {
+				components = append(components, mapping.Component)
+				break
+			}
+		}
+	}
+	sort.Strings(components)
+	return components
+}
+
+// getMappedComponents returns the names of all components described by the
+// specified ComponentMappings.
+func getMappedComponents(mappings []ComponentMapping) []string {
+	components := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		components[i] = mapping.Component
+	}
+	return dedupeAndSort(components)
+}
+
+// maxPushWebhookCommits is the maximum number of commits GitHub includes in a
+// push webhook's payload.
+const maxPushWebhookCommits = 20
+
+// isTruncatedPush returns a boolean indicating whether the specified webhook is
+// a push webhook whose payload may not list every path changed by the push.
+// This is the case when the push includes more commits than GitHub includes in
+// the payload, and also when the push creates a new branch or tag or is forced,
+// since the commits in the payload then do not account for every change.
+func isTruncatedPush(webhook interface{}) bool {
+	w, ok := webhook.(*github.PushEvent)
+	if !ok {
+		return false
+	}
+	return len(w.Commits) >= maxPushWebhookCommits ||
+		w.GetCreated() ||
+		w.GetForced()
+}
+
+// getChangedPaths returns the paths of all files changed by the push or PR
+// conveyed by the specified webhook. For push webhooks, changed paths are taken
+// from the webhook's payload and may therefore be incomplete (see
+// isTruncatedPush). For pull_request webhooks whose action signals
+// new or updated changes, changed paths are retrieved using the GitHub API. For
+// all other webhooks, nil is returned.
+func (s *service) getChangedPaths(
+	ctx context.Context,
+	appID int64,
+	webhook interface{},
+) ([]string, error) {
+	var changedPaths []string
+	switch w := webhook.(type) {
+	case *github.PushEvent:
+		for _, commit := range w.Commits {
+			changedPaths = append(changedPaths, commit.Added...)
+			changedPaths = append(changedPaths, commit.Removed...)
+			changedPaths = append(changedPaths, commit.Modified...)
+		}
+	case *github.PullRequestEvent:
+		// Most PR actions, such as labeling or commenting, say nothing about the
+		// PR's changes, so we don't spend API calls on them.
+		if !isPRChangesAction(w.GetAction()) {
+			return nil, nil
+		}
+		var err error
+		if changedPaths, err = s.listPRFilesFn(
+			ctx,
+			s.config.GitHubApps[appID],
+			w.GetInstallation().GetID(),
+			w.GetRepo().GetOwner().GetLogin(),
+			w.GetRepo().GetName(),
+			w.GetPullRequest().GetNumber(),
+		); err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error listing files changed by %s PR #%d",
+				w.GetRepo().GetFullName(),
+				w.GetPullRequest().GetNumber(),
+			)
+		}
+	}
+	return changedPaths, nil
+}
+
+// isPRChangesAction returns a boolean indicating whether pull_request webhooks
+// with the specified action signal that a PR's changes are new or have been
+// updated.
+func isPRChangesAction(action string) bool {
+	switch action {
+	case "opened", "synchronize", "reopened", "ready_for_review":
+		return true
+	}
+	return false
+}
+
+// applyComponents modifies the specified events to reflect the specified
+// affected components. By default, each event is labeled with a
+// component.<name> label for each affected component. If the service is
+// configured to emit per-component events, each event is instead replaced with
+// one event per affected component, labeled with a component label. If no
+// components are affected, the events are returned unmodified.
+func (s *service) applyComponents(
+	events []sdk.Event,
+	components []string,
+) []sdk.Event {
+	if len(components) == 0 {
+		return events
+	}
+	if s.config.ComponentEventsEnabled {
+		componentEvents := make([]sdk.Event, 0, len(events)*len(components))
+		for _, event := range events {
+			for _, component := range components {
+				componentEvent := event
+				componentEvent.Labels =
+					copyLabels(event.Labels, map[string]string{"component": component})
+				componentEvents = append(componentEvents, componentEvent)
+			}
+		}
+		return componentEvents
+	}
+	componentLabels := make(map[string]string, len(components))
+	for _, component := range components {
+		componentLabels[fmt.Sprintf("component.%s", component)] = "true"
+	}
+	for i := range events {
+		events[i].Labels = copyLabels(events[i].Labels, componentLabels)
+	}
+	return events
+}
+
+// copyLabels returns a new map containing all of the specified labels and all
+// of the specified additional labels. Additional labels take precedence.
+func copyLabels(
+	labels map[string]string,
+	additionalLabels map[string]string,
+) map[string]string {
+	newLabels := make(map[string]string, len(labels)+len(additionalLabels))
+	for k, v := range labels {
+		newLabels[k] = v
+	}
+	for k, v := range additionalLabels {
+		newLabels[k] = v
+	}
+	return newLabels
+}
+
+// listPRFiles returns the paths of all files changed by the specified PR. For
+// renamed files, both the old and new paths are included.
+func (s *service) listPRFiles(
+	ctx context.Context,
+	app ghlib.App,
+	installationID int64,
+	repoOwner string,
+	repoName string,
+	number int,
+) ([]string, error) {
+	ghClient, err := ghlib.NewClient(
+		ctx,
+		app.AppID,
+		installationID,
+		[]byte(app.APIKey),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error creating new client for installation %d",
+			installationID,
+		)
+	}
+	paths := []string{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, res, err := ghClient.PullRequests.ListFiles(
+			ctx,
+			repoOwner,
+			repoName,
+			number,
+			opts,
+		)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error listing files for pullrequest %d for %s/%s",
+				number,
+				repoOwner,
+				repoName,
+			)
+		}
+		for _, file := range files {
+			paths = append(paths, file.GetFilename())
+			if file.GetPreviousFilename() != "" {
+				paths = append(paths, file.GetPreviousFilename())
+			}
+		}
+		if res.NextPage == 0 {
+			return paths, nil
+		}
+		opts.Page = res.NextPage
+	}
+}
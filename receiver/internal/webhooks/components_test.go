@@ -0,0 +1,481 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentMappingValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mapping    ComponentMapping
+		assertions func(error)
+	}{
+		{
+			name:    "no component name",
+			mapping: ComponentMapping{},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has no component name")
+			},
+		},
+		{
+			name: "invalid repo pattern",
+			mapping: ComponentMapping{
+				Component: "api",
+				Repos:     []string{"brigadecore/["},
+				Paths:     []string{"api/**"},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid repo pattern")
+			},
+		},
+		{
+			name: "no paths",
+			mapping: ComponentMapping{
+				Component: "api",
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any paths")
+			},
+		},
+		{
+			name: "invalid path pattern",
+			mapping: ComponentMapping{
+				Component: "api",
+				Paths:     []string{"api/["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid path pattern")
+			},
+		},
+		{
+			name: "valid",
+			mapping: ComponentMapping{
+				Component: "api",
+				Repos:     []string{"brigadecore/brigade"},
+				Paths:     []string{"v2/apiserver/**", "v2/go.mod"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(testCase.mapping.Validate())
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{pattern: "README.md", path: "README.md", matches: true},
+		{pattern: "README.md", path: "docs/README.md", matches: false},
+		{pattern: "*.md", path: "CONTRIBUTING.md", matches: true},
+		{pattern: "*.md", path: "docs/CI_CD.md", matches: false},
+		{pattern: "docs/*", path: "docs/CI_CD.md", matches: true},
+		{pattern: "docs/*", path: "docs/img/logo.png", matches: false},
+		{pattern: "docs/**", path: "docs/img/logo.png", matches: true},
+		{pattern: "**/*.md", path: "CONTRIBUTING.md", matches: true},
+		{pattern: "**/*.md", path: "docs/img/README.md", matches: true},
+		{pattern: "**/*.md", path: "docs/img/logo.png", matches: false},
+		{pattern: "v2/**/main.go", path: "v2/apiserver/main.go", matches: true},
+		{pattern: "v2/**/main.go", path: "v2/main.go", matches: true},
+		{pattern: "v2/**/main.go", path: "v1/main.go", matches: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.pattern+" "+testCase.path, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.matches,
+				matchPath(testCase.pattern, testCase.path),
+			)
+		})
+	}
+}
+
+func TestGetComponentMappings(t *testing.T) {
+	testRepo := &github.Repository{
+		FullName: github.String("brigadecore/brigade"),
+	}
+	testMappings := []ComponentMapping{
+		{
+			Component: "apiserver",
+			Repos:     []string{"brigadecore/brigade"},
+			Paths:     []string{"v2/apiserver/**"},
+		},
+		{
+			Component: "docs",
+			Paths:     []string{"docs/**", "**/*.md"},
+		},
+		{
+			Component: "brigadier",
+			Repos:     []string{"brigadecore/brigadier"},
+			Paths:     []string{"**"},
+		},
+	}
+	testCases := []struct {
+		name             string
+		webhook          interface{}
+		expectedMappings []ComponentMapping
+	}{
+		{
+			name: "webhook of no interest",
+			webhook: &github.IssuesEvent{
+				Repo: testRepo,
+			},
+		},
+		{
+			name: "push webhook",
+			webhook: &github.PushEvent{
+				Repo: &github.PushEventRepository{
+					FullName: testRepo.FullName,
+				},
+			},
+			expectedMappings: testMappings[:2],
+		},
+		{
+			name: "pull_request webhook",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+			},
+			expectedMappings: testMappings[:2],
+		},
+		{
+			name: "pull_request webhook not changing files",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("labeled"),
+				Repo:   testRepo,
+			},
+		},
+		{
+			name: "no mappings apply to the repo",
+			webhook: &github.PushEvent{
+				Repo: &github.PushEventRepository{
+					FullName: github.String("krancour/brigade"),
+				},
+			},
+			expectedMappings: testMappings[1:2],
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: ServiceConfig{
+					ComponentMappings: testMappings,
+				},
+			}
+			require.Equal(
+				t,
+				testCase.expectedMappings,
+				s.getComponentMappings(testCase.webhook),
+			)
+		})
+	}
+}
+
+func TestGetAffectedComponents(t *testing.T) {
+	testMappings := []ComponentMapping{
+		{
+			Component: "apiserver",
+			Paths:     []string{"v2/apiserver/**"},
+		},
+		{
+			Component: "docs",
+			Paths:     []string{"docs/**", "**/*.md"},
+		},
+		{
+			Component: "scheduler",
+			Paths:     []string{"v2/scheduler/**"},
+		},
+	}
+	testCases := []struct {
+		name               string
+		changedPaths       []string
+		expectedComponents []string
+	}{
+		{
+			name:               "no changed paths",
+			expectedComponents: []string{},
+		},
+		{
+			name: "components affected",
+			changedPaths: []string{
+				"v2/scheduler/main.go",
+				"README.md",
+				"v2/scheduler/scheduler.go",
+			},
+			expectedComponents: []string{"docs", "scheduler"},
+		},
+		{
+			name:               "no components affected",
+			changedPaths:       []string{"Makefile"},
+			expectedComponents: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedComponents,
+				getAffectedComponents(testMappings, testCase.changedPaths),
+			)
+		})
+	}
+}
+
+func TestIsTruncatedPush(t *testing.T) {
+	testCases := []struct {
+		name      string
+		webhook   interface{}
+		truncated bool
+	}{
+		{
+			name:      "webhook of no interest",
+			webhook:   &github.IssuesEvent{},
+			truncated: false,
+		},
+		{
+			name: "push webhook listing all commits",
+			webhook: &github.PushEvent{
+				Commits: make([]*github.HeadCommit, maxPushWebhookCommits-1),
+			},
+			truncated: false,
+		},
+		{
+			name: "push webhook listing the maximum number of commits",
+			webhook: &github.PushEvent{
+				Commits: make([]*github.HeadCommit, maxPushWebhookCommits),
+			},
+			truncated: true,
+		},
+		{
+			name: "push webhook creating a branch",
+			webhook: &github.PushEvent{
+				Created: github.Bool(true),
+			},
+			truncated: true,
+		},
+		{
+			name: "forced push webhook",
+			webhook: &github.PushEvent{
+				Forced: github.Bool(true),
+			},
+			truncated: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.truncated, isTruncatedPush(testCase.webhook))
+		})
+	}
+}
+
+func TestGetChangedPaths(t *testing.T) {
+	testRepo := &github.Repository{
+		FullName: github.String("brigadecore/brigade"),
+		Name:     github.String("brigade"),
+		Owner: &github.User{
+			Login: github.String("brigadecore"),
+		},
+	}
+	testCases := []struct {
+		name       string
+		webhook    interface{}
+		prFiles    []string
+		prFilesErr error
+		assertions func([]string, error)
+	}{
+		{
+			name: "webhook of no interest",
+			webhook: &github.IssuesEvent{
+				Repo: testRepo,
+			},
+			assertions: func(paths []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, paths)
+			},
+		},
+		{
+			name: "push webhook",
+			webhook: &github.PushEvent{
+				Commits: []*github.HeadCommit{
+					{
+						Modified: []string{"v2/scheduler/main.go"},
+					},
+					{
+						Added:   []string{"README.md"},
+						Removed: []string{"Makefile"},
+					},
+				},
+			},
+			assertions: func(paths []string, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{"v2/scheduler/main.go", "README.md", "Makefile"},
+					paths,
+				)
+			},
+		},
+		{
+			name: "pull_request webhook",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("synchronize"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					Number: github.Int(42),
+				},
+			},
+			prFiles: []string{"v2/apiserver/main.go"},
+			assertions: func(paths []string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"v2/apiserver/main.go"}, paths)
+			},
+		},
+		{
+			name: "pull_request webhook not changing files",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("labeled"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					Number: github.Int(42),
+				},
+			},
+			prFiles: []string{"v2/apiserver/main.go"},
+			assertions: func(paths []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, paths)
+			},
+		},
+		{
+			name: "pull_request webhook; error listing files",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Repo:   testRepo,
+				PullRequest: &github.PullRequest{
+					Number: github.Int(42),
+				},
+			},
+			prFilesErr: errors.New("something went wrong"),
+			assertions: func(_ []string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "something went wrong")
+				require.Contains(t, err.Error(), "error listing files")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				listPRFilesFn: func(
+					_ context.Context,
+					_ ghlib.App,
+					_ int64,
+					repoOwner string,
+					repoName string,
+					number int,
+				) ([]string, error) {
+					require.Equal(t, "brigadecore", repoOwner)
+					require.Equal(t, "brigade", repoName)
+					require.Equal(t, 42, number)
+					return testCase.prFiles, testCase.prFilesErr
+				},
+			}
+			testCase.assertions(
+				s.getChangedPaths(context.Background(), 42, testCase.webhook),
+			)
+		})
+	}
+}
+
+func TestApplyComponents(t *testing.T) {
+	testEvents := func() []sdk.Event {
+		labels := map[string]string{"appID": "42"}
+		return []sdk.Event{
+			{Type: "pull_request:closed", Labels: labels},
+			{Type: "pull_request:merged", Labels: labels},
+		}
+	}
+	testCases := []struct {
+		name                   string
+		componentEventsEnabled bool
+		components             []string
+		assertions             func([]sdk.Event)
+	}{
+		{
+			name:       "no components affected",
+			components: []string{},
+			assertions: func(events []sdk.Event) {
+				require.Equal(t, testEvents(), events)
+			},
+		},
+		{
+			name:       "labels",
+			components: []string{"apiserver", "docs"},
+			assertions: func(events []sdk.Event) {
+				require.Len(t, events, 2)
+				for _, event := range events {
+					require.Equal(
+						t,
+						map[string]string{
+							"appID":               "42",
+							"component.apiserver": "true",
+							"component.docs":      "true",
+						},
+						event.Labels,
+					)
+				}
+			},
+		},
+		{
+			name:                   "per-component events",
+			componentEventsEnabled: true,
+			components:             []string{"apiserver", "docs"},
+			assertions: func(events []sdk.Event) {
+				require.Len(t, events, 4)
+				require.Equal(t, "pull_request:closed", events[0].Type)
+				require.Equal(
+					t,
+					map[string]string{"appID": "42", "component": "apiserver"},
+					events[0].Labels,
+				)
+				require.Equal(t, "pull_request:closed", events[1].Type)
+				require.Equal(
+					t,
+					map[string]string{"appID": "42", "component": "docs"},
+					events[1].Labels,
+				)
+				require.Equal(t, "pull_request:merged", events[2].Type)
+				require.Equal(t, "pull_request:merged", events[3].Type)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: ServiceConfig{
+					ComponentEventsEnabled: testCase.componentEventsEnabled,
+				},
+			}
+			testCase.assertions(
+				s.applyComponents(testEvents(), testCase.components),
+			)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"strconv"
+
+	"github.com/google/go-github/v33/github"
+)
+
+// discussionEvent represents a discussion webhook, which the version of
+// go-github we depend on does not support.
+type discussionEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Discussion   *discussion          `json:"discussion,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// discussionCommentEvent represents a discussion_comment webhook, which the
+// version of go-github we depend on does not support.
+type discussionCommentEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Comment      *discussionComment   `json:"comment,omitempty"`
+	Discussion   *discussion          `json:"discussion,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// discussion represents a GitHub Discussion.
+type discussion struct {
+	Number            *int                `json:"number,omitempty"`
+	Title             *string             `json:"title,omitempty"`
+	Body              *string             `json:"body,omitempty"`
+	Category          *discussionCategory `json:"category,omitempty"`
+	AuthorAssociation *string             `json:"author_association,omitempty"`
+	User              *github.User        `json:"user,omitempty"`
+}
+
+// discussionCategory represents the category a GitHub Discussion belongs to.
+type discussionCategory struct {
+	Name *string `json:"name,omitempty"`
+	Slug *string `json:"slug,omitempty"`
+}
+
+// discussionComment represents a comment on a GitHub Discussion.
+type discussionComment struct {
+	Body              *string      `json:"body,omitempty"`
+	AuthorAssociation *string      `json:"author_association,omitempty"`
+	User              *github.User `json:"user,omitempty"`
+}
+
+// getDiscussionLabels returns labels conveying the number and category of the
+// specified discussion. Any of these that are unknown are omitted.
+func getDiscussionLabels(d *discussion) map[string]string {
+	labels := map[string]string{}
+	if d.GetNumber() != 0 {
+		labels["discussionNumber"] = strconv.Itoa(d.GetNumber())
+	}
+	if d.GetCategory().GetSlug() != "" {
+		labels["category"] = d.GetCategory().GetSlug()
+	}
+	return labels
+}
+
+func (d *discussionEvent) GetAction() string {
+	if d == nil || d.Action == nil {
+		return ""
+	}
+	return *d.Action
+}
+
+func (d *discussionEvent) GetDiscussion() *discussion {
+	if d == nil {
+		return nil
+	}
+	return d.Discussion
+}
+
+func (d *discussionEvent) GetRepo() *github.Repository {
+	if d == nil {
+		return nil
+	}
+	return d.Repo
+}
+
+func (d *discussionEvent) GetSender() *github.User {
+	if d == nil {
+		return nil
+	}
+	return d.Sender
+}
+
+func (d *discussionEvent) GetInstallation() *github.Installation {
+	if d == nil {
+		return nil
+	}
+	return d.Installation
+}
+
+func (d *discussionCommentEvent) GetAction() string {
+	if d == nil || d.Action == nil {
+		return ""
+	}
+	return *d.Action
+}
+
+func (d *discussionCommentEvent) GetComment() *discussionComment {
+	if d == nil {
+		return nil
+	}
+	return d.Comment
+}
+
+func (d *discussionCommentEvent) GetDiscussion() *discussion {
+	if d == nil {
+		return nil
+	}
+	return d.Discussion
+}
+
+func (d *discussionCommentEvent) GetRepo() *github.Repository {
+	if d == nil {
+		return nil
+	}
+	return d.Repo
+}
+
+func (d *discussionCommentEvent) GetSender() *github.User {
+	if d == nil {
+		return nil
+	}
+	return d.Sender
+}
+
+func (d *discussionCommentEvent) GetInstallation() *github.Installation {
+	if d == nil {
+		return nil
+	}
+	return d.Installation
+}
+
+func (d *discussion) GetNumber() int {
+	if d == nil || d.Number == nil {
+		return 0
+	}
+	return *d.Number
+}
+
+func (d *discussion) GetTitle() string {
+	if d == nil || d.Title == nil {
+		return ""
+	}
+	return *d.Title
+}
+
+func (d *discussion) GetBody() string {
+	if d == nil || d.Body == nil {
+		return ""
+	}
+	return *d.Body
+}
+
+func (d *discussion) GetCategory() *discussionCategory {
+	if d == nil {
+		return nil
+	}
+	return d.Category
+}
+
+func (d *discussion) GetAuthorAssociation() string {
+	if d == nil || d.AuthorAssociation == nil {
+		return ""
+	}
+	return *d.AuthorAssociation
+}
+
+func (d *discussionCategory) GetSlug() string {
+	if d == nil || d.Slug == nil {
+		return ""
+	}
+	return *d.Slug
+}
+
+func (d *discussionComment) GetBody() string {
+	if d == nil || d.Body == nil {
+		return ""
+	}
+	return *d.Body
+}
+
+func (d *discussionComment) GetAuthorAssociation() string {
+	if d == nil || d.AuthorAssociation == nil {
+		return ""
+	}
+	return *d.AuthorAssociation
+}
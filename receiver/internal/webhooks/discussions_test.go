@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDiscussionLabels(t *testing.T) {
+	testCases := []struct {
+		name           string
+		discussion     *discussion
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "nil discussion",
+			expectedLabels: map[string]string{},
+		},
+		{
+			name: "discussion with category",
+			discussion: &discussion{
+				Number: github.Int(42),
+				Category: &discussionCategory{
+					Name: github.String("Q&A"),
+					Slug: github.String("q-a"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"discussionNumber": "42",
+				"category":         "q-a",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getDiscussionLabels(testCase.discussion),
+			)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// dispatchEventTypeRegex matches any character that is not permitted in the
+// portion of an event type derived from a repository_dispatch webhook's
+// event_type.
+var dispatchEventTypeRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// dispatchLabelPrefix is the prefix applied to the names of all labels derived
+// from a repository_dispatch webhook's client_payload, so that external systems
+// cannot set labels, such as branch or tag, that the gateway itself sets and
+// relies upon.
+const dispatchLabelPrefix = "payload."
+
+// DispatchPolicy permits repository_dispatch webhooks having particular event
+// types to be emitted as events for all repositories matching any of the
+// policy's Repos patterns. repository_dispatch webhooks not permitted by any
+// policy are ignored.
+type DispatchPolicy struct {
+	// Name is a human-friendly name for the policy. It is used only for logging
+	// which policy was applied to any repository_dispatch webhook.
+	Name string `json:"name"`
+	// Repos enumerates glob patterns that select the repositories to which this
+	// policy applies. A pattern that does not contain a "/" (e.g. "brigadecore")
+	// is matched against a repository's owner (i.e. an org or user). A pattern
+	// that does contain a "/" (e.g. "brigadecore/brigade-*") is matched against a
+	// repository's full name.
+	Repos []string `json:"repos"`
+	// EventTypes enumerates glob patterns that select the dispatch event types
+	// (e.g. "deploy-*") this policy permits.
+	EventTypes []string `json:"eventTypes"`
+	// PayloadKeys optionally enumerates glob patterns that select keys from the
+	// dispatch's client_payload that should be applied to the emitted event as
+	// labels. Nested keys are flattened using "." as a separator (e.g.
+	// "deploy.env"). Only keys having string, number, or boolean values are
+	// eligible. Label names are prefixed with "payload." (e.g.
+	// "payload.deploy.env"), so keys named the same as labels set by the
+	// gateway itself (e.g. "branch") cannot collide with them.
+	PayloadKeys []string `json:"payloadKeys,omitempty"`
+}
+
+// Validate returns an error if the DispatchPolicy is invalid.
+func (d DispatchPolicy) Validate() error {
+	if d.Name == "" {
+		return errors.New("dispatch policy has no name")
+	}
+	if len(d.Repos) == 0 {
+		return errors.Errorf(
+			"dispatch policy %q does not specify any repos",
+			d.Name,
+		)
+	}
+	if err := validateRepoPatterns(d.Repos); err != nil {
+		return errors.Wrapf(err, "dispatch policy %q is invalid", d.Name)
+	}
+	if len(d.EventTypes) == 0 {
+		return errors.Errorf(
+			"dispatch policy %q does not specify any event types",
+			d.Name,
+		)
+	}
+	for _, pattern := range d.EventTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(
+				err,
+				"dispatch policy %q has invalid event type pattern %q",
+				d.Name,
+				pattern,
+			)
+		}
+	}
+	for _, pattern := range d.PayloadKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(
+				err,
+				"dispatch policy %q has invalid payload key pattern %q",
+				d.Name,
+				pattern,
+			)
+		}
+	}
+	return nil
+}
+
+// matches returns a boolean indicating whether the DispatchPolicy permits the
+// specified dispatch event type for the repository having the specified full
+// name (i.e. <owner>/<name>).
+func (d DispatchPolicy) matches(repoFullName string, eventType string) bool {
+	return repoMatches(d.Repos, repoFullName) &&
+		globMatches(d.EventTypes, eventType)
+}
+
+// getDispatchPolicy returns the first DispatchPolicy that permits the
+// specified dispatch event type for the repository having the specified full
+// name. If no policy does, nil is returned.
+func (s *service) getDispatchPolicy(
+	repoFullName string,
+	eventType string,
+) *DispatchPolicy {
+	for i, policy := range s.config.DispatchPolicies {
+		if policy.matches(repoFullName, eventType) {
+			return &s.config.DispatchPolicies[i]
+		}
+	}
+	return nil
+}
+
+// getDispatchEventType returns the type of event to be emitted for a
+// repository_dispatch webhook having the specified event_type. All such events
+// are namespaced with a "dispatch:" prefix and any characters that are not
+// alphanumeric or one of "_", ".", or "-" are replaced with a "-" so that
+// external systems cannot masquerade as any other kind of event.
+func getDispatchEventType(eventType string) string {
+	return fmt.Sprintf(
+		"dispatch:%s",
+		dispatchEventTypeRegex.ReplaceAllString(eventType, "-"),
+	)
+}
+
+// getDispatchLabels flattens the specified client_payload and returns, as
+// labels, the keys selected by the specified glob patterns, prefixed with
+// "payload.", along with their values. Keys whose values are not strings,
+// numbers, or booleans are omitted.
+func getDispatchLabels(
+	clientPayload json.RawMessage,
+	patterns []string,
+) map[string]string {
+	labels := map[string]string{}
+	if len(patterns) == 0 || len(clientPayload) == 0 {
+		return labels
+	}
+	payload := map[string]interface{}{}
+	if err := json.Unmarshal(clientPayload, &payload); err != nil {
+		// The payload isn't a JSON object, so there are no keys to select.
+		return labels
+	}
+	for key, value := range flattenDispatchPayload("", payload) {
+		if globMatches(patterns, key) {
+			labels[dispatchLabelPrefix+key] = value
+		}
+	}
+	return labels
+}
+
+// flattenDispatchPayload returns a map of all keys in the specified (possibly
+// nested) payload having string, number, or boolean values to their values,
+// rendered as strings. Nested keys are joined to the specified prefix and to
+// one another using "." as a separator.
+func flattenDispatchPayload(
+	prefix string,
+	payload map[string]interface{},
+) map[string]string {
+	flattened := map[string]string{}
+	for key, value := range payload {
+		fullKey := key
+		if prefix != "" {
+			fullKey = fmt.Sprintf("%s.%s", prefix, key)
+		}
+		switch value := value.(type) {
+		case string:
+			flattened[fullKey] = value
+		case float64:
+			flattened[fullKey] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			flattened[fullKey] = strconv.FormatBool(value)
+		case map[string]interface{}:
+			for k, v := range flattenDispatchPayload(fullKey, value) {
+				flattened[k] = v
+			}
+		}
+	}
+	return flattened
+}
+
+// globMatches returns a boolean indicating whether any of the specified glob
+// patterns matches the specified value.
+func globMatches(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated at startup, so we can ignore the error.
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
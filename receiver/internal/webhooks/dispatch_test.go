@@ -0,0 +1,220 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     DispatchPolicy
+		assertions func(error)
+	}{
+		{
+			name:   "no name",
+			policy: DispatchPolicy{},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has no name")
+			},
+		},
+		{
+			name: "no repos",
+			policy: DispatchPolicy{
+				Name: "foo",
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any repos")
+			},
+		},
+		{
+			name: "invalid repo pattern",
+			policy: DispatchPolicy{
+				Name:  "foo",
+				Repos: []string{"brigadecore/["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid repo pattern")
+			},
+		},
+		{
+			name: "no event types",
+			policy: DispatchPolicy{
+				Name:  "foo",
+				Repos: []string{"brigadecore"},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not specify any event types")
+			},
+		},
+		{
+			name: "invalid event type pattern",
+			policy: DispatchPolicy{
+				Name:       "foo",
+				Repos:      []string{"brigadecore"},
+				EventTypes: []string{"deploy-["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid event type pattern")
+			},
+		},
+		{
+			name: "invalid payload key pattern",
+			policy: DispatchPolicy{
+				Name:        "foo",
+				Repos:       []string{"brigadecore"},
+				EventTypes:  []string{"deploy-*"},
+				PayloadKeys: []string{"["},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid payload key pattern")
+			},
+		},
+		{
+			name: "payload key named like a gateway label",
+			policy: DispatchPolicy{
+				Name:        "foo",
+				Repos:       []string{"brigadecore"},
+				EventTypes:  []string{"deploy-*"},
+				PayloadKeys: []string{"env", "branch"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "valid",
+			policy: DispatchPolicy{
+				Name:        "foo",
+				Repos:       []string{"brigadecore"},
+				EventTypes:  []string{"deploy-*"},
+				PayloadKeys: []string{"env"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(testCase.policy.Validate())
+		})
+	}
+}
+
+func TestGetDispatchPolicy(t *testing.T) {
+	s := &service{
+		config: ServiceConfig{
+			DispatchPolicies: []DispatchPolicy{
+				{
+					Name:       "deployments",
+					Repos:      []string{"brigadecore/brigade-*"},
+					EventTypes: []string{"deploy-*"},
+				},
+				{
+					Name:       "everything",
+					Repos:      []string{"brigadecore"},
+					EventTypes: []string{"*"},
+				},
+			},
+		},
+	}
+	const testRepo = "brigadecore/brigade-github-gateway"
+	policy := s.getDispatchPolicy(testRepo, "deploy-x")
+	require.NotNil(t, policy)
+	require.Equal(t, "deployments", policy.Name)
+	policy = s.getDispatchPolicy(testRepo, "build")
+	require.NotNil(t, policy)
+	require.Equal(t, "everything", policy.Name)
+	require.Nil(t, s.getDispatchPolicy("krancour/foo", "deploy-x"))
+}
+
+func TestGetDispatchEventType(t *testing.T) {
+	testCases := []struct {
+		eventType         string
+		expectedEventType string
+	}{
+		{
+			eventType:         "deploy-staging",
+			expectedEventType: "dispatch:deploy-staging",
+		},
+		{
+			eventType:         "ci:pipeline_requested",
+			expectedEventType: "dispatch:ci-pipeline_requested",
+		},
+		{
+			eventType:         "deploy to/prod!",
+			expectedEventType: "dispatch:deploy-to-prod-",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.eventType, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedEventType,
+				getDispatchEventType(testCase.eventType),
+			)
+		})
+	}
+}
+
+func TestGetDispatchLabels(t *testing.T) {
+	testPayload := json.RawMessage(`{
+		"env": "staging",
+		"replicas": 3,
+		"canary": true,
+		"image": {
+			"repo": "brigadecore/foo",
+			"tag": "v1.2.3"
+		},
+		"hosts": ["a", "b"],
+		"secret": "shh"
+	}`)
+	testCases := []struct {
+		name           string
+		payload        json.RawMessage
+		patterns       []string
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "no patterns",
+			payload:        testPayload,
+			expectedLabels: map[string]string{},
+		},
+		{
+			name:           "payload is not an object",
+			payload:        json.RawMessage(`"foo"`),
+			patterns:       []string{"*"},
+			expectedLabels: map[string]string{},
+		},
+		{
+			name:     "selected keys",
+			payload:  testPayload,
+			patterns: []string{"env", "replicas", "canary", "image.*", "hosts"},
+			expectedLabels: map[string]string{
+				"payload.env":        "staging",
+				"payload.replicas":   "3",
+				"payload.canary":     "true",
+				"payload.image.repo": "brigadecore/foo",
+				"payload.image.tag":  "v1.2.3",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getDispatchLabels(testCase.payload, testCase.patterns),
+			)
+		})
+	}
+}
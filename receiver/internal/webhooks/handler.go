@@ -48,8 +48,13 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	events, err :=
-		h.service.Handle(r.Context(), appID, github.WebHookType(r), payload)
+	events, err := h.service.Handle(
+		r.Context(),
+		appID,
+		github.WebHookType(r),
+		github.DeliveryID(r),
+		payload,
+	)
 	if err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	// normalizedPayloadVersion is the version of the normalized payload schema.
+	// It must be incremented whenever the schema changes in a manner that is not
+	// backwards compatible.
+	normalizedPayloadVersion = "v1"
+	// normalizedPayloadSidecarKey is the key under which a normalized payload is
+	// added to the original webhook's JSON payload in sidecar mode.
+	normalizedPayloadSidecarKey = "brigade_normalized"
+)
+
+// normalizedPayload is a versioned document conveying the details of a webhook
+// that are most commonly of interest to scripts, in a form that does not vary
+// by webhook type. Details that are not applicable to the webhook or that are
+// not present in its payload are omitted.
+type normalizedPayload struct {
+	// Version is the version of the normalized payload schema.
+	Version string `json:"version"`
+	// Type is the type of the event.
+	Type string `json:"type"`
+	// Action is the webhook's action.
+	Action string `json:"action,omitempty"`
+	// Repo is the full name (i.e. <owner>/<name>) of the repository involved.
+	Repo string `json:"repo,omitempty"`
+	// RepoURL is the URL of the repository involved.
+	RepoURL string `json:"repoURL,omitempty"`
+	// Ref is the ref involved.
+	Ref string `json:"ref,omitempty"`
+	// SHA is the commit involved.
+	SHA string `json:"sha,omitempty"`
+	// PRNumber is the number of the PR involved.
+	PRNumber int `json:"prNumber,omitempty"`
+	// Author is the login (or, for commits, the name) of the author of the PR,
+	// issue, comment, commit, or release involved.
+	Author string `json:"author,omitempty"`
+	// Sender is the login of the user who triggered the webhook.
+	Sender string `json:"sender,omitempty"`
+	// ChangedFiles enumerates the paths, relative to the root of the
+	// repository, of all files changed by the push or PR involved. For large,
+	// forced, or branch-creating pushes, this may be incomplete.
+	ChangedFiles []string `json:"changedFiles,omitempty"`
+}
+
+// applyNormalizedPayloads attaches a normalized payload to each of the
+// specified events, either as the event's payload or as a sidecar field added
+// to the original webhook's JSON payload, depending on how the service is
+// configured. The specified normalized payload carries any details extracted
+// by Handle's type switch and the specified changed paths are those determined
+// by Handle. All remaining details are filled in here.
+func (s *service) applyNormalizedPayloads(
+	webhook interface{},
+	normalized normalizedPayload,
+	changedPaths []string,
+	events []sdk.Event,
+) ([]sdk.Event, error) {
+	normalized.Version = normalizedPayloadVersion
+	if w, ok := webhook.(actionGetter); ok {
+		normalized.Action = w.GetAction()
+	}
+	if w, ok := webhook.(senderGetter); ok {
+		normalized.Sender = w.GetSender().GetLogin()
+	}
+	normalized.ChangedFiles = dedupeAndSort(changedPaths)
+	for i, event := range events {
+		eventNormalized := normalized
+		eventNormalized.Type = event.Type
+		eventNormalized.Repo = event.Qualifiers["repo"]
+		eventNormalized.RepoURL = getRepoURL(event.Payload)
+		if event.Git != nil {
+			eventNormalized.Ref = event.Git.Ref
+			eventNormalized.SHA = event.Git.Commit
+		} else {
+			// For deleted refs, event.Git is deliberately left empty, but the ref is
+			// conveyed by a label.
+			eventNormalized.Ref = event.Labels["ref"]
+		}
+		normalizedBytes, err := json.Marshal(eventNormalized)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error marshaling normalized payload for event %q",
+				event.Type,
+			)
+		}
+		if s.config.NormalizedPayloadMode == NormalizedPayloadModePayload {
+			events[i].Payload = string(normalizedBytes)
+			continue
+		}
+		payload := map[string]json.RawMessage{}
+		if err = json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error adding normalized payload to payload for event %q",
+				event.Type,
+			)
+		}
+		payload[normalizedPayloadSidecarKey] = normalizedBytes
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error adding normalized payload to payload for event %q",
+				event.Type,
+			)
+		}
+		events[i].Payload = string(payloadBytes)
+	}
+	return events, nil
+}
+
+// getRepoURL returns the URL of the repository that the specified payload
+// pertains to. The payload may be either a webhook's original JSON payload or
+// a normalized payload. If the payload does not pertain to a repository, an
+// empty string is returned.
+func getRepoURL(payload string) string {
+	p := struct {
+		Repository *struct {
+			HTMLURL string `json:"html_url"`
+		} `json:"repository"`
+		RepoURL string `json:"repoURL"`
+	}{}
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return ""
+	}
+	if p.Repository != nil && p.Repository.HTMLURL != "" {
+		return p.Repository.HTMLURL
+	}
+	return p.RepoURL
+}
+
+// dedupeAndSort returns the specified values, in sorted order, with duplicates
+// removed.
+func dedupeAndSort(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	deduped := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := set[value]; !ok {
+			set[value] = struct{}{}
+			deduped = append(deduped, value)
+		}
+	}
+	sort.Strings(deduped)
+	return deduped
+}
@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyNormalizedPayloads(t *testing.T) {
+	const testPayload = `{"repository":{"html_url":"https://github.com/brigadecore/brigade"}}` // nolint: lll
+	testPRWebhook := &github.PullRequestEvent{
+		Action: github.String("opened"),
+		Sender: &github.User{
+			Login: github.String("krancour"),
+		},
+		Repo: &github.Repository{
+			Name: github.String("brigade"),
+			Owner: &github.User{
+				Login: github.String("brigadecore"),
+			},
+		},
+		PullRequest: &github.PullRequest{
+			Number: github.Int(42),
+		},
+	}
+	testEvents := func() []sdk.Event {
+		return []sdk.Event{
+			{
+				Type: "pull_request:opened",
+				Qualifiers: map[string]string{
+					"repo": "brigadecore/brigade",
+				},
+				Git: &sdk.GitDetails{
+					Commit: "1234567",
+					Ref:    "refs/pull/42/head",
+				},
+				Payload: testPayload,
+			},
+		}
+	}
+	testNormalized := normalizedPayload{
+		PRNumber: 42,
+		Author:   "carolynvs",
+	}
+	testCases := []struct {
+		name         string
+		service      *service
+		webhook      interface{}
+		normalized   normalizedPayload
+		changedPaths []string
+		events       []sdk.Event
+		assertions   func([]sdk.Event, error)
+	}{
+		{
+			name: "payload mode",
+			service: &service{
+				config: ServiceConfig{
+					NormalizedPayloadMode: NormalizedPayloadModePayload,
+				},
+			},
+			webhook:      testPRWebhook,
+			normalized:   testNormalized,
+			changedPaths: []string{"b.go", "a.go", "b.go"},
+			events:       testEvents(),
+			assertions: func(events []sdk.Event, err error) {
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				normalized := normalizedPayload{}
+				err = json.Unmarshal([]byte(events[0].Payload), &normalized)
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					normalizedPayload{
+						Version:      "v1",
+						Type:         "pull_request:opened",
+						Action:       "opened",
+						Repo:         "brigadecore/brigade",
+						RepoURL:      "https://github.com/brigadecore/brigade",
+						Ref:          "refs/pull/42/head",
+						SHA:          "1234567",
+						PRNumber:     42,
+						Author:       "carolynvs",
+						Sender:       "krancour",
+						ChangedFiles: []string{"a.go", "b.go"},
+					},
+					normalized,
+				)
+			},
+		},
+		{
+			name: "sidecar mode; no changed paths",
+			service: &service{
+				config: ServiceConfig{
+					NormalizedPayloadMode: NormalizedPayloadModeSidecar,
+				},
+			},
+			webhook:    testPRWebhook,
+			normalized: testNormalized,
+			events:     testEvents(),
+			assertions: func(events []sdk.Event, err error) {
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				payload := struct {
+					Repository *struct {
+						HTMLURL string `json:"html_url"`
+					} `json:"repository"`
+					Normalized *normalizedPayload `json:"brigade_normalized"`
+				}{}
+				err = json.Unmarshal([]byte(events[0].Payload), &payload)
+				require.NoError(t, err)
+				// The original payload should be intact
+				require.NotNil(t, payload.Repository)
+				require.Equal(
+					t,
+					"https://github.com/brigadecore/brigade",
+					payload.Repository.HTMLURL,
+				)
+				require.NotNil(t, payload.Normalized)
+				require.Equal(t, "v1", payload.Normalized.Version)
+				require.Equal(t, 42, payload.Normalized.PRNumber)
+				require.Empty(t, payload.Normalized.ChangedFiles)
+			},
+		},
+		{
+			name: "sidecar mode; deleted ref",
+			service: &service{
+				config: ServiceConfig{
+					NormalizedPayloadMode: NormalizedPayloadModeSidecar,
+				},
+			},
+			webhook: &github.DeleteEvent{},
+			events: []sdk.Event{
+				{
+					Type: "delete:branch",
+					Labels: map[string]string{
+						"ref": "refs/heads/feature",
+					},
+					Payload: "{}",
+				},
+			},
+			assertions: func(events []sdk.Event, err error) {
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				require.JSONEq(
+					t,
+					`{"brigade_normalized":{"version":"v1","type":"delete:branch","ref":"refs/heads/feature"}}`, // nolint: lll
+					events[0].Payload,
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				testCase.service.applyNormalizedPayloads(
+					testCase.webhook,
+					testCase.normalized,
+					testCase.changedPaths,
+					testCase.events,
+				),
+			)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package webhooks
+
+// getOrgLabels returns a copy of the specified labels, which describe the
+// teams, members, or other details involved in an org-level webhook, omitting
+// any whose values are unknown. Org-level events are qualified by org rather
+// than by repo, so these labels are the only means by which projects can
+// subscribe to such events in a more fine-grained manner.
+func getOrgLabels(labels map[string]string) map[string]string {
+	orgLabels := map[string]string{}
+	for key, value := range labels {
+		if value != "" {
+			orgLabels[key] = value
+		}
+	}
+	return orgLabels
+}
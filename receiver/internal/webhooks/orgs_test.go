@@ -0,0 +1,20 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrgLabels(t *testing.T) {
+	require.Equal(
+		t,
+		map[string]string{
+			"team": "maintainers",
+		},
+		getOrgLabels(map[string]string{
+			"team":   "maintainers",
+			"member": "",
+		}),
+	)
+}
@@ -0,0 +1,213 @@
+package webhooks
+
+import "github.com/google/go-github/v33/github"
+
+// packageEvent represents a package webhook. It is used in lieu of
+// github.PackageEvent, whose package property lacks the ecosystem and details
+// of container images, such as their tags.
+type packageEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Package      *registryPackage     `json:"package,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// registryPackageEvent represents a registry_package webhook, which the version
+// of go-github we depend on does not support.
+type registryPackageEvent struct {
+	Action          *string              `json:"action,omitempty"`
+	RegistryPackage *registryPackage     `json:"registry_package,omitempty"`
+	Repo            *github.Repository   `json:"repository,omitempty"`
+	Sender          *github.User         `json:"sender,omitempty"`
+	Installation    *github.Installation `json:"installation,omitempty"`
+}
+
+// registryPackage represents a package published to GitHub Packages (e.g. a
+// container image published to GHCR).
+type registryPackage struct {
+	Name           *string                 `json:"name,omitempty"`
+	Ecosystem      *string                 `json:"ecosystem,omitempty"`
+	PackageType    *string                 `json:"package_type,omitempty"`
+	PackageVersion *registryPackageVersion `json:"package_version,omitempty"`
+}
+
+// registryPackageVersion represents a specific version of a package.
+type registryPackageVersion struct {
+	Version           *string            `json:"version,omitempty"`
+	TagName           *string            `json:"tag_name,omitempty"`
+	TargetOID         *string            `json:"target_oid,omitempty"`
+	ContainerMetadata *containerMetadata `json:"container_metadata,omitempty"`
+}
+
+// containerMetadata represents details specific to a container image.
+type containerMetadata struct {
+	Tag *containerTag `json:"tag,omitempty"`
+}
+
+// containerTag represents a container image's tag.
+type containerTag struct {
+	Name   *string `json:"name,omitempty"`
+	Digest *string `json:"digest,omitempty"`
+}
+
+// getPackageLabels returns labels conveying the name, version, ecosystem, and
+// tag of the specified package. Any of these that are unknown are omitted. The
+// tag is conveyed by a packageTag label, since it is not necessarily a Git tag
+// and should not be mistaken for one by subscribers or CI/CD mappings.
+func getPackageLabels(pkg *registryPackage) map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{
+		"package":    pkg.GetName(),
+		"version":    pkg.GetPackageVersion().GetVersion(),
+		"ecosystem":  pkg.GetEcosystem(),
+		"packageTag": pkg.GetPackageVersion().GetTag(),
+	} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+func (p *packageEvent) GetAction() string {
+	if p == nil || p.Action == nil {
+		return ""
+	}
+	return *p.Action
+}
+
+func (p *packageEvent) GetPackage() *registryPackage {
+	if p == nil {
+		return nil
+	}
+	return p.Package
+}
+
+func (p *packageEvent) GetRepo() *github.Repository {
+	if p == nil {
+		return nil
+	}
+	return p.Repo
+}
+
+func (p *packageEvent) GetSender() *github.User {
+	if p == nil {
+		return nil
+	}
+	return p.Sender
+}
+
+func (p *packageEvent) GetInstallation() *github.Installation {
+	if p == nil {
+		return nil
+	}
+	return p.Installation
+}
+
+func (r *registryPackageEvent) GetAction() string {
+	if r == nil || r.Action == nil {
+		return ""
+	}
+	return *r.Action
+}
+
+func (r *registryPackageEvent) GetRegistryPackage() *registryPackage {
+	if r == nil {
+		return nil
+	}
+	return r.RegistryPackage
+}
+
+func (r *registryPackageEvent) GetRepo() *github.Repository {
+	if r == nil {
+		return nil
+	}
+	return r.Repo
+}
+
+func (r *registryPackageEvent) GetSender() *github.User {
+	if r == nil {
+		return nil
+	}
+	return r.Sender
+}
+
+func (r *registryPackageEvent) GetInstallation() *github.Installation {
+	if r == nil {
+		return nil
+	}
+	return r.Installation
+}
+
+func (r *registryPackage) GetName() string {
+	if r == nil || r.Name == nil {
+		return ""
+	}
+	return *r.Name
+}
+
+// GetEcosystem returns the package's ecosystem or, if that is unknown, its
+// package type. The two are equivalent, but not every payload includes both.
+func (r *registryPackage) GetEcosystem() string {
+	if r == nil {
+		return ""
+	}
+	if r.Ecosystem != nil && *r.Ecosystem != "" {
+		return *r.Ecosystem
+	}
+	if r.PackageType == nil {
+		return ""
+	}
+	return *r.PackageType
+}
+
+func (r *registryPackage) GetPackageVersion() *registryPackageVersion {
+	if r == nil {
+		return nil
+	}
+	return r.PackageVersion
+}
+
+func (r *registryPackageVersion) GetVersion() string {
+	if r == nil || r.Version == nil {
+		return ""
+	}
+	return *r.Version
+}
+
+func (r *registryPackageVersion) GetTargetOID() string {
+	if r == nil || r.TargetOID == nil {
+		return ""
+	}
+	return *r.TargetOID
+}
+
+// GetTag returns the container image tag of the package version or, if it is
+// not a container image, the Git tag it was published from, if any.
+func (r *registryPackageVersion) GetTag() string {
+	if r == nil {
+		return ""
+	}
+	if tag := r.ContainerMetadata.GetTag().GetName(); tag != "" {
+		return tag
+	}
+	if r.TagName == nil {
+		return ""
+	}
+	return *r.TagName
+}
+
+func (c *containerMetadata) GetTag() *containerTag {
+	if c == nil {
+		return nil
+	}
+	return c.Tag
+}
+
+func (c *containerTag) GetName() string {
+	if c == nil || c.Name == nil {
+		return ""
+	}
+	return *c.Name
+}
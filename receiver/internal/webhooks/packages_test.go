@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPackageLabels(t *testing.T) {
+	testCases := []struct {
+		name           string
+		pkg            *registryPackage
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "nil package",
+			expectedLabels: map[string]string{},
+		},
+		{
+			name: "container image",
+			pkg: &registryPackage{
+				Name:        github.String("brigade-github-gateway"),
+				PackageType: github.String("CONTAINER"),
+				PackageVersion: &registryPackageVersion{
+					Version: github.String("sha256:abcdef"),
+					ContainerMetadata: &containerMetadata{
+						Tag: &containerTag{
+							Name: github.String("v1.2.3"),
+						},
+					},
+				},
+			},
+			expectedLabels: map[string]string{
+				"package":    "brigade-github-gateway",
+				"version":    "sha256:abcdef",
+				"ecosystem":  "CONTAINER",
+				"packageTag": "v1.2.3",
+			},
+		},
+		{
+			name: "npm package",
+			pkg: &registryPackage{
+				Name:        github.String("brigade-utils"),
+				Ecosystem:   github.String("npm"),
+				PackageType: github.String("NPM"),
+				PackageVersion: &registryPackageVersion{
+					Version: github.String("1.2.3"),
+					TagName: github.String("v1.2.3"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"package":    "brigade-utils",
+				"version":    "1.2.3",
+				"ecosystem":  "npm",
+				"packageTag": "v1.2.3",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getPackageLabels(testCase.pkg),
+			)
+		})
+	}
+}
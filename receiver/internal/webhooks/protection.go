@@ -0,0 +1,364 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+)
+
+// requiredStatusChecksRuleType is the type of repository ruleset rule that
+// requires status checks to pass before a ref can be updated.
+const requiredStatusChecksRuleType = "required_status_checks"
+
+// branchProtectionRuleEvent represents a branch_protection_rule webhook, which
+// the version of go-github we depend on does not support.
+type branchProtectionRuleEvent struct {
+	Action       *string                      `json:"action,omitempty"`
+	Rule         *branchProtectionRule        `json:"rule,omitempty"`
+	Changes      *branchProtectionRuleChanges `json:"changes,omitempty"`
+	Repo         *github.Repository           `json:"repository,omitempty"`
+	Sender       *github.User                 `json:"sender,omitempty"`
+	Installation *github.Installation         `json:"installation,omitempty"`
+}
+
+// branchProtectionRule represents a branch protection rule. Its name is the
+// pattern that selects the branches it protects.
+type branchProtectionRule struct {
+	Name                 *string  `json:"name,omitempty"`
+	RequiredStatusChecks []string `json:"required_status_checks,omitempty"`
+}
+
+// branchProtectionRuleChanges represents the previous values of any branch
+// protection rule properties that were edited.
+type branchProtectionRuleChanges struct {
+	RequiredStatusChecks *struct {
+		From []string `json:"from,omitempty"`
+	} `json:"required_status_checks,omitempty"`
+}
+
+// repositoryRulesetEvent represents a repository_ruleset webhook, which the
+// version of go-github we depend on does not support.
+type repositoryRulesetEvent struct {
+	Action            *string                   `json:"action,omitempty"`
+	RepositoryRuleset *repositoryRuleset        `json:"repository_ruleset,omitempty"` // nolint: lll
+	Changes           *repositoryRulesetChanges `json:"changes,omitempty"`
+	Repo              *github.Repository        `json:"repository,omitempty"`
+	Org               *github.Organization      `json:"organization,omitempty"`
+	Sender            *github.User              `json:"sender,omitempty"`
+	Installation      *github.Installation      `json:"installation,omitempty"`
+}
+
+// repositoryRuleset represents a named set of rules that apply to the refs
+// selected by its conditions.
+type repositoryRuleset struct {
+	Name       *string           `json:"name,omitempty"`
+	Conditions *rulesetCondition `json:"conditions,omitempty"`
+	Rules      []*rulesetRule    `json:"rules,omitempty"`
+}
+
+// rulesetCondition represents the conditions that select the refs a ruleset
+// applies to.
+type rulesetCondition struct {
+	RefName *struct {
+		Include []string `json:"include,omitempty"`
+	} `json:"ref_name,omitempty"`
+}
+
+// rulesetRule represents a single rule within a ruleset.
+type rulesetRule struct {
+	Type       *string            `json:"type,omitempty"`
+	Parameters *rulesetRuleParams `json:"parameters,omitempty"`
+}
+
+// rulesetRuleParams represents the parameters of a rule. Only the parameters
+// of required_status_checks rules are of interest to us.
+type rulesetRuleParams struct {
+	RequiredStatusChecks []*struct {
+		Context *string `json:"context,omitempty"`
+	} `json:"required_status_checks,omitempty"`
+}
+
+// repositoryRulesetChanges represents changes to a ruleset's rules.
+type repositoryRulesetChanges struct {
+	Rules *struct {
+		Deleted []*rulesetRule `json:"deleted,omitempty"`
+		Updated []*struct {
+			Rule    *rulesetRule `json:"rule,omitempty"`
+			Changes *struct {
+				Configuration *struct {
+					// From is the rule's previous parameters, serialized as JSON.
+					From *string `json:"from,omitempty"`
+				} `json:"configuration,omitempty"`
+			} `json:"changes,omitempty"`
+		} `json:"updated,omitempty"`
+	} `json:"rules,omitempty"`
+}
+
+// getProtectionLabels returns labels conveying the pattern(s) that select the
+// branches affected by a change to branch protection, the actor who made the
+// change, and any required status checks the change removed. When any checks
+// were removed, the event is also labeled requiredChecksRemoved=true so that
+// projects can subscribe specifically to such changes.
+func getProtectionLabels(
+	branchPattern string,
+	actor string,
+	removedChecks []string,
+) map[string]string {
+	labels := map[string]string{}
+	if branchPattern != "" {
+		labels["branchPattern"] = branchPattern
+	}
+	if actor != "" {
+		labels["actor"] = actor
+	}
+	if len(removedChecks) > 0 {
+		labels["removedRequiredChecks"] = strings.Join(removedChecks, ",")
+		labels["requiredChecksRemoved"] = "true"
+	}
+	return labels
+}
+
+// getRemovedChecks returns, in sorted order, all checks that appear in the
+// specified previous list of checks but not in the specified current list.
+func getRemovedChecks(previous []string, current []string) []string {
+	currentSet := make(map[string]struct{}, len(current))
+	for _, check := range current {
+		currentSet[check] = struct{}{}
+	}
+	removedSet := map[string]struct{}{}
+	for _, check := range previous {
+		if _, ok := currentSet[check]; !ok {
+			removedSet[check] = struct{}{}
+		}
+	}
+	removed := make([]string, 0, len(removedSet))
+	for check := range removedSet {
+		removed = append(removed, check)
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// getRemovedChecks returns all required status checks that were removed from
+// the branch protection rule. If the rule was deleted, these are all of its
+// required status checks.
+func (b *branchProtectionRuleEvent) getRemovedChecks() []string {
+	if b.GetAction() == "deleted" {
+		return getRemovedChecks(b.GetRule().GetRequiredStatusChecks(), nil)
+	}
+	if b.GetChanges().GetRequiredStatusChecks() == nil {
+		return nil
+	}
+	return getRemovedChecks(
+		b.GetChanges().GetRequiredStatusChecks(),
+		b.GetRule().GetRequiredStatusChecks(),
+	)
+}
+
+// getRemovedChecks returns all required status checks that were removed from
+// the ruleset, whether by removing or updating required_status_checks rules. If
+// the ruleset was deleted, these are all of its required status checks.
+func (r *repositoryRulesetEvent) getRemovedChecks() []string {
+	var previous, current []string
+	if r.GetAction() == "deleted" {
+		for _, rule := range r.GetRepositoryRuleset().GetRules() {
+			previous = append(previous, rule.getRequiredStatusChecks()...)
+		}
+		return getRemovedChecks(previous, nil)
+	}
+	if r == nil || r.Changes == nil || r.Changes.Rules == nil {
+		return nil
+	}
+	for _, rule := range r.Changes.Rules.Deleted {
+		previous = append(previous, rule.getRequiredStatusChecks()...)
+	}
+	for _, updated := range r.Changes.Rules.Updated {
+		if updated == nil ||
+			updated.Rule.GetType() != requiredStatusChecksRuleType {
+			continue
+		}
+		current = append(current, updated.Rule.getRequiredStatusChecks()...)
+		if updated.Changes == nil || updated.Changes.Configuration == nil ||
+			updated.Changes.Configuration.From == nil {
+			continue
+		}
+		params := &rulesetRuleParams{}
+		if err := json.Unmarshal(
+			[]byte(*updated.Changes.Configuration.From),
+			params,
+		); err != nil {
+			// We can't tell what the rule's previous configuration was, so there's
+			// nothing to compare the current configuration to.
+			continue
+		}
+		previous = append(previous, params.getRequiredStatusChecks()...)
+	}
+	return getRemovedChecks(previous, current)
+}
+
+// getBranchPattern returns the ref name pattern(s) that select the refs a
+// ruleset applies to, separated by commas.
+func (r *repositoryRuleset) getBranchPattern() string {
+	if r == nil || r.Conditions == nil || r.Conditions.RefName == nil {
+		return ""
+	}
+	return strings.Join(r.Conditions.RefName.Include, ",")
+}
+
+// getRequiredStatusChecks returns the contexts of all status checks required by
+// the rule, if it is a required_status_checks rule.
+func (r *rulesetRule) getRequiredStatusChecks() []string {
+	if r.GetType() != requiredStatusChecksRuleType {
+		return nil
+	}
+	return r.Parameters.getRequiredStatusChecks()
+}
+
+// getRequiredStatusChecks returns the contexts of all status checks required by
+// the parameters.
+func (r *rulesetRuleParams) getRequiredStatusChecks() []string {
+	if r == nil {
+		return nil
+	}
+	checks := make([]string, 0, len(r.RequiredStatusChecks))
+	for _, check := range r.RequiredStatusChecks {
+		if check != nil && check.Context != nil {
+			checks = append(checks, *check.Context)
+		}
+	}
+	return checks
+}
+
+func (b *branchProtectionRuleEvent) GetAction() string {
+	if b == nil || b.Action == nil {
+		return ""
+	}
+	return *b.Action
+}
+
+func (b *branchProtectionRuleEvent) GetRule() *branchProtectionRule {
+	if b == nil {
+		return nil
+	}
+	return b.Rule
+}
+
+func (b *branchProtectionRuleEvent) GetChanges() *branchProtectionRuleChanges {
+	if b == nil {
+		return nil
+	}
+	return b.Changes
+}
+
+func (b *branchProtectionRuleEvent) GetRepo() *github.Repository {
+	if b == nil {
+		return nil
+	}
+	return b.Repo
+}
+
+func (b *branchProtectionRuleEvent) GetSender() *github.User {
+	if b == nil {
+		return nil
+	}
+	return b.Sender
+}
+
+func (b *branchProtectionRuleEvent) GetInstallation() *github.Installation {
+	if b == nil {
+		return nil
+	}
+	return b.Installation
+}
+
+func (b *branchProtectionRule) GetName() string {
+	if b == nil || b.Name == nil {
+		return ""
+	}
+	return *b.Name
+}
+
+func (b *branchProtectionRule) GetRequiredStatusChecks() []string {
+	if b == nil {
+		return nil
+	}
+	return b.RequiredStatusChecks
+}
+
+// GetRequiredStatusChecks returns the rule's previous required status checks
+// if they were changed. Otherwise, nil is returned.
+func (b *branchProtectionRuleChanges) GetRequiredStatusChecks() []string {
+	if b == nil || b.RequiredStatusChecks == nil {
+		return nil
+	}
+	if b.RequiredStatusChecks.From == nil {
+		// The rule previously required no checks, but this is still a change.
+		return []string{}
+	}
+	return b.RequiredStatusChecks.From
+}
+
+func (r *repositoryRulesetEvent) GetAction() string {
+	if r == nil || r.Action == nil {
+		return ""
+	}
+	return *r.Action
+}
+
+func (r *repositoryRulesetEvent) GetRepositoryRuleset() *repositoryRuleset {
+	if r == nil {
+		return nil
+	}
+	return r.RepositoryRuleset
+}
+
+func (r *repositoryRulesetEvent) GetRepo() *github.Repository {
+	if r == nil {
+		return nil
+	}
+	return r.Repo
+}
+
+func (r *repositoryRulesetEvent) GetOrg() *github.Organization {
+	if r == nil {
+		return nil
+	}
+	return r.Org
+}
+
+func (r *repositoryRulesetEvent) GetSender() *github.User {
+	if r == nil {
+		return nil
+	}
+	return r.Sender
+}
+
+func (r *repositoryRulesetEvent) GetInstallation() *github.Installation {
+	if r == nil {
+		return nil
+	}
+	return r.Installation
+}
+
+func (r *repositoryRuleset) GetName() string {
+	if r == nil || r.Name == nil {
+		return ""
+	}
+	return *r.Name
+}
+
+func (r *repositoryRuleset) GetRules() []*rulesetRule {
+	if r == nil {
+		return nil
+	}
+	return r.Rules
+}
+
+func (r *rulesetRule) GetType() string {
+	if r == nil || r.Type == nil {
+		return ""
+	}
+	return *r.Type
+}
@@ -0,0 +1,151 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProtectionLabels(t *testing.T) {
+	testCases := []struct {
+		name           string
+		branchPattern  string
+		actor          string
+		removedChecks  []string
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "no details",
+			expectedLabels: map[string]string{},
+		},
+		{
+			name:          "no checks removed",
+			branchPattern: "main",
+			actor:         "krancour",
+			removedChecks: []string{},
+			expectedLabels: map[string]string{
+				"branchPattern": "main",
+				"actor":         "krancour",
+			},
+		},
+		{
+			name:          "checks removed",
+			branchPattern: "release/*",
+			actor:         "krancour",
+			removedChecks: []string{"my-project:lint", "my-project:test"},
+			expectedLabels: map[string]string{
+				"branchPattern":         "release/*",
+				"actor":                 "krancour",
+				"removedRequiredChecks": "my-project:lint,my-project:test",
+				"requiredChecksRemoved": "true",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getProtectionLabels(
+					testCase.branchPattern,
+					testCase.actor,
+					testCase.removedChecks,
+				),
+			)
+		})
+	}
+}
+
+func TestBranchProtectionRuleEventGetRemovedChecks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		payload        string
+		expectedChecks []string
+	}{
+		{
+			name:           "rule created",
+			payload:        `{"action":"created","rule":{"required_status_checks":["a"]}}`, // nolint: lll
+			expectedChecks: nil,
+		},
+		{
+			name:           "rule edited; checks not changed",
+			payload:        `{"action":"edited","rule":{"required_status_checks":["a"]}}`, // nolint: lll
+			expectedChecks: nil,
+		},
+		{
+			name:           "rule edited; checks added",
+			payload:        `{"action":"edited","rule":{"required_status_checks":["a","b"]},"changes":{"required_status_checks":{"from":["a"]}}}`, // nolint: lll
+			expectedChecks: []string{},
+		},
+		{
+			name:           "rule edited; checks removed",
+			payload:        `{"action":"edited","rule":{"required_status_checks":["b"]},"changes":{"required_status_checks":{"from":["c","a","b"]}}}`, // nolint: lll
+			expectedChecks: []string{"a", "c"},
+		},
+		{
+			name:           "rule deleted",
+			payload:        `{"action":"deleted","rule":{"required_status_checks":["b","a"]}}`, // nolint: lll
+			expectedChecks: []string{"a", "b"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			webhook, err :=
+				parseWebHook("branch_protection_rule", []byte(testCase.payload))
+			require.NoError(t, err)
+			// nolint: forcetypeassert
+			require.Equal(
+				t,
+				testCase.expectedChecks,
+				webhook.(*branchProtectionRuleEvent).getRemovedChecks(),
+			)
+		})
+	}
+}
+
+func TestRepositoryRulesetEventGetRemovedChecks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		payload        string
+		expectedChecks []string
+	}{
+		{
+			name:           "ruleset edited; rules not changed",
+			payload:        `{"action":"edited"}`,
+			expectedChecks: nil,
+		},
+		{
+			name:           "ruleset edited; rule deleted",
+			payload:        `{"action":"edited","changes":{"rules":{"deleted":[{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"a"}]}},{"type":"deletion"}]}}}`, // nolint: lll
+			expectedChecks: []string{"a"},
+		},
+		{
+			name:           "ruleset edited; rule updated",
+			payload:        `{"action":"edited","changes":{"rules":{"updated":[{"rule":{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"b"}]}},"changes":{"configuration":{"from":"{\"required_status_checks\":[{\"context\":\"a\"},{\"context\":\"b\"}]}"}}}]}}}`, // nolint: lll
+			expectedChecks: []string{"a"},
+		},
+		{
+			name:           "ruleset edited; previous configuration unparseable",
+			payload:        `{"action":"edited","changes":{"rules":{"updated":[{"rule":{"type":"required_status_checks"},"changes":{"configuration":{"from":"bogus"}}}]}}}`, // nolint: lll
+			expectedChecks: []string{},
+		},
+		{
+			name:           "ruleset deleted",
+			payload:        `{"action":"deleted","repository_ruleset":{"rules":[{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"a"}]}}]}}`, // nolint: lll
+			expectedChecks: []string{"a"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			webhook, err :=
+				parseWebHook("repository_ruleset", []byte(testCase.payload))
+			require.NoError(t, err)
+			// nolint: forcetypeassert
+			require.Equal(
+				t,
+				testCase.expectedChecks,
+				webhook.(*repositoryRulesetEvent).getRemovedChecks(),
+			)
+		})
+	}
+}
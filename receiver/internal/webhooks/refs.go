@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+)
+
+// semverTagRegex matches tags that are semantic versions, optionally prefixed
+// with a "v". See https://semver.org.
+var semverTagRegex = regexp.MustCompile(
+	`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` + // nolint: lll
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`,
+)
+
+// getRefTypeEvent derives, from the specified event, a more specific event
+// whose type is suffixed with the specified ref type (i.e. "branch" or "tag").
+// e.g. A push event for a tag is used to derive a push:tag event. The derived
+// event is labeled with the branch or tag name and, for tags that are semantic
+// versions, the components of the version. If the ref type is not one of
+// "branch" or "tag", nil is returned.
+func getRefTypeEvent(event sdk.Event, refType string, ref string) *sdk.Event {
+	var labels map[string]string
+	switch refType {
+	case "branch":
+		labels = map[string]string{"branch": ref}
+	case "tag":
+		labels = getSemverLabels(ref)
+		labels["tag"] = ref
+	default:
+		return nil
+	}
+	refTypeEvent := event
+	refTypeEvent.Type = fmt.Sprintf("%s:%s", event.Type, refType)
+	refTypeEvent.Labels = copyLabels(event.Labels, labels)
+	return &refTypeEvent
+}
+
+// getSemverLabels returns labels conveying the components of the specified tag
+// if it is a semantic version. If it is not, an empty map is returned.
+func getSemverLabels(tag string) map[string]string {
+	labels := map[string]string{}
+	submatches := semverTagRegex.FindStringSubmatch(tag)
+	if submatches == nil {
+		return labels
+	}
+	labels["semverMajor"] = submatches[1]
+	labels["semverMinor"] = submatches[2]
+	labels["semverPatch"] = submatches[3]
+	labels["prerelease"] = "false"
+	if submatches[4] != "" {
+		labels["semverPrerelease"] = submatches[4]
+		labels["prerelease"] = "true"
+	}
+	return labels
+}
+
+// getDeletedRefLabels returns labels conveying which ref was deleted. The ref
+// label is always the fully qualified ref (e.g. refs/heads/main), even if the
+// specified ref is a short name, so that branches and tags having the same name
+// cannot be confused. The previousSHA label, if known, conveys the commit the
+// ref last referenced.
+func getDeletedRefLabels(
+	refType string,
+	ref string,
+	previousSHA string,
+) map[string]string {
+	labels := map[string]string{}
+	if ref == "" {
+		return labels
+	}
+	labels["ref"] = ref
+	switch refType {
+	case "branch":
+		labels["refType"] = refType
+		if !branchRefRegex.MatchString(ref) {
+			labels["ref"] = fmt.Sprintf("refs/heads/%s", ref)
+		}
+	case "tag":
+		labels["refType"] = refType
+		if !tagRefRegex.MatchString(ref) {
+			labels["ref"] = fmt.Sprintf("refs/tags/%s", ref)
+		}
+	}
+	if previousSHA != "" {
+		labels["previousSHA"] = previousSHA
+	}
+	return labels
+}
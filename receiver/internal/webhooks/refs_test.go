@@ -0,0 +1,168 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRefTypeEvent(t *testing.T) {
+	testEvent := sdk.Event{
+		Type:   "create",
+		Labels: map[string]string{"appID": "42"},
+	}
+	testCases := []struct {
+		name       string
+		refType    string
+		ref        string
+		assertions func(*sdk.Event)
+	}{
+		{
+			name:    "unknown ref type",
+			refType: "bogus",
+			ref:     "foo",
+			assertions: func(event *sdk.Event) {
+				require.Nil(t, event)
+			},
+		},
+		{
+			name:    "branch",
+			refType: "branch",
+			ref:     "main",
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "create:branch", event.Type)
+				require.Equal(
+					t,
+					map[string]string{"appID": "42", "branch": "main"},
+					event.Labels,
+				)
+			},
+		},
+		{
+			name:    "tag",
+			refType: "tag",
+			ref:     "foo",
+			assertions: func(event *sdk.Event) {
+				require.NotNil(t, event)
+				require.Equal(t, "create:tag", event.Type)
+				require.Equal(
+					t,
+					map[string]string{"appID": "42", "tag": "foo"},
+					event.Labels,
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				getRefTypeEvent(testEvent, testCase.refType, testCase.ref),
+			)
+			// The original event's labels should never be modified
+			require.Equal(t, map[string]string{"appID": "42"}, testEvent.Labels)
+		})
+	}
+}
+
+func TestGetSemverLabels(t *testing.T) {
+	testCases := []struct {
+		tag            string
+		expectedLabels map[string]string
+	}{
+		{
+			tag:            "latest",
+			expectedLabels: map[string]string{},
+		},
+		{
+			tag:            "v1.2",
+			expectedLabels: map[string]string{},
+		},
+		{
+			tag: "1.2.3",
+			expectedLabels: map[string]string{
+				"semverMajor": "1",
+				"semverMinor": "2",
+				"semverPatch": "3",
+				"prerelease":  "false",
+			},
+		},
+		{
+			tag: "v2.0.0-beta.1+build.5",
+			expectedLabels: map[string]string{
+				"semverMajor":      "2",
+				"semverMinor":      "0",
+				"semverPatch":      "0",
+				"semverPrerelease": "beta.1",
+				"prerelease":       "true",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.tag, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getSemverLabels(testCase.tag),
+			)
+		})
+	}
+}
+
+func TestGetDeletedRefLabels(t *testing.T) {
+	testCases := []struct {
+		name           string
+		refType        string
+		ref            string
+		previousSHA    string
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "no ref",
+			expectedLabels: map[string]string{},
+		},
+		{
+			name:    "short branch name",
+			refType: "branch",
+			ref:     "main",
+			expectedLabels: map[string]string{
+				"ref":     "refs/heads/main",
+				"refType": "branch",
+			},
+		},
+		{
+			name:    "short tag name",
+			refType: "tag",
+			ref:     "v1.0.0",
+			expectedLabels: map[string]string{
+				"ref":     "refs/tags/v1.0.0",
+				"refType": "tag",
+			},
+		},
+		{
+			name:        "fully qualified ref with previous SHA",
+			refType:     "tag",
+			ref:         "refs/tags/v1.0.0",
+			previousSHA: "1234567",
+			expectedLabels: map[string]string{
+				"ref":         "refs/tags/v1.0.0",
+				"refType":     "tag",
+				"previousSHA": "1234567",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getDeletedRefLabels(
+					testCase.refType,
+					testCase.ref,
+					testCase.previousSHA,
+				),
+			)
+		})
+	}
+}
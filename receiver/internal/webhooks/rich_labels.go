@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+)
+
+// actionGetter is implemented by all webhook types having an action property.
+type actionGetter interface {
+	GetAction() string
+}
+
+// senderGetter is implemented by all webhook types having a sender property.
+type senderGetter interface {
+	GetSender() *github.User
+}
+
+// getRichLabels extracts commonly useful details from the specified webhook and
+// returns them as a map of labels suitable for being applied to any event
+// emitted as a result of the webhook. These permit Brigade projects to
+// subscribe to events in a more fine-grained manner than is possible using
+// event types and the repo qualifier alone. Details that are not applicable to
+// the webhook or that are not present in its payload are omitted.
+func getRichLabels(
+	webhook interface{},
+	deliveryID string,
+) map[string]string {
+	labels := map[string]string{}
+	setLabel := func(key, value string) {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+
+	setLabel("deliveryID", deliveryID)
+	if w, ok := webhook.(actionGetter); ok {
+		setLabel("action", w.GetAction())
+	}
+	if w, ok := webhook.(senderGetter); ok {
+		setLabel("sender", w.GetSender().GetLogin())
+	}
+
+	setPRLabels := func(pr *github.PullRequest) {
+		setLabel("branch", pr.GetHead().GetRef())
+		setLabel("baseBranch", pr.GetBase().GetRef())
+		if pr.GetNumber() != 0 {
+			setLabel("prNumber", strconv.Itoa(pr.GetNumber()))
+		}
+	}
+
+	switch w := webhook.(type) {
+	case *github.CheckRunEvent:
+		setLabel("branch", w.GetCheckRun().GetCheckSuite().GetHeadBranch())
+	case *github.CheckSuiteEvent:
+		setLabel("branch", w.GetCheckSuite().GetHeadBranch())
+		// A check suite can, in theory, pertain to more than one PR. We only
+		// label the event with PR details when it's unambiguous.
+		if len(w.GetCheckSuite().PullRequests) == 1 {
+			pr := w.GetCheckSuite().PullRequests[0]
+			setLabel("baseBranch", pr.GetBase().GetRef())
+			if pr.GetNumber() != 0 {
+				setLabel("prNumber", strconv.Itoa(pr.GetNumber()))
+			}
+		}
+	case *github.CreateEvent:
+		setRefTypeLabel(labels, w.GetRefType(), w.GetRef())
+	case *github.DeleteEvent:
+		setRefTypeLabel(labels, w.GetRefType(), w.GetRef())
+	case *github.IssueCommentEvent:
+		if w.GetIssue().IsPullRequest() && w.GetIssue().GetNumber() != 0 {
+			setLabel("prNumber", strconv.Itoa(w.GetIssue().GetNumber()))
+		}
+		setLabel("author_association", w.GetComment().GetAuthorAssociation())
+	case *github.IssuesEvent:
+		setLabel("author_association", w.GetIssue().GetAuthorAssociation())
+		// For the labeled and unlabeled actions, the label that was added or
+		// removed.
+		setLabel("label", w.GetLabel().GetName())
+	case *github.PullRequestEvent:
+		setPRLabels(w.GetPullRequest())
+		setLabel("author_association", w.GetPullRequest().GetAuthorAssociation())
+		setLabel("label", w.GetLabel().GetName())
+	case *github.PullRequestReviewEvent:
+		setPRLabels(w.GetPullRequest())
+		setLabel("author_association", w.GetReview().GetAuthorAssociation())
+	case *github.PullRequestReviewCommentEvent:
+		setPRLabels(w.GetPullRequest())
+		setLabel("author_association", w.GetComment().GetAuthorAssociation())
+	case *github.PushEvent:
+		if refSubmatches :=
+			branchRefRegex.FindStringSubmatch(w.GetRef()); len(refSubmatches) == 2 {
+			setLabel("branch", refSubmatches[1])
+		} else if refSubmatches :=
+			tagRefRegex.FindStringSubmatch(w.GetRef()); len(refSubmatches) == 2 {
+			setLabel("tag", refSubmatches[1])
+		}
+	case *github.ReleaseEvent:
+		setLabel("tag", w.GetRelease().GetTagName())
+	case *discussionEvent:
+		setLabel(
+			"author_association",
+			w.GetDiscussion().GetAuthorAssociation(),
+		)
+	case *discussionCommentEvent:
+		setLabel("author_association", w.GetComment().GetAuthorAssociation())
+	case *mergeGroupEvent:
+		if refSubmatches := branchRefRegex.FindStringSubmatch(
+			w.GetMergeGroup().GetBaseRef(),
+		); len(refSubmatches) == 2 {
+			setLabel("baseBranch", refSubmatches[1])
+		}
+	case *workflowJobEvent:
+		setLabel("branch", w.GetWorkflowJob().GetHeadBranch())
+	case *workflowRunEvent:
+		setLabel("branch", w.GetWorkflowRun().GetHeadBranch())
+	}
+
+	return labels
+}
+
+// setRefTypeLabel sets either a branch or tag label, as appropriate, for
+// webhooks that convey a short ref name alongside its type.
+func setRefTypeLabel(labels map[string]string, refType string, ref string) {
+	if ref == "" {
+		return
+	}
+	switch strings.ToLower(refType) {
+	case "branch":
+		labels["branch"] = ref
+	case "tag":
+		labels["tag"] = ref
+	}
+}
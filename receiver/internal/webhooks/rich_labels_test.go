@@ -0,0 +1,216 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRichLabels(t *testing.T) {
+	const testDeliveryID = "72d3162e-cc78-11e3-81ab-4c9367dc0958"
+	testSender := &github.User{
+		Login: github.String("krancour"),
+	}
+	testPR := &github.PullRequest{
+		Number:            github.Int(42),
+		AuthorAssociation: github.String("CONTRIBUTOR"),
+		Head: &github.PullRequestBranch{
+			Ref: github.String("feature"),
+		},
+		Base: &github.PullRequestBranch{
+			Ref: github.String("main"),
+		},
+	}
+	testCases := []struct {
+		name           string
+		webhook        interface{}
+		deliveryID     string
+		expectedLabels map[string]string
+	}{
+		{
+			name: "check_run webhook",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("rerequested"),
+				Sender: testSender,
+				CheckRun: &github.CheckRun{
+					CheckSuite: &github.CheckSuite{
+						HeadBranch: github.String("main"),
+					},
+				},
+			},
+			deliveryID: testDeliveryID,
+			expectedLabels: map[string]string{
+				"deliveryID": testDeliveryID,
+				"action":     "rerequested",
+				"sender":     "krancour",
+				"branch":     "main",
+			},
+		},
+		{
+			name: "check_suite webhook for a single PR",
+			webhook: &github.CheckSuiteEvent{
+				Action: github.String("requested"),
+				CheckSuite: &github.CheckSuite{
+					HeadBranch:   github.String("feature"),
+					PullRequests: []*github.PullRequest{testPR},
+				},
+			},
+			expectedLabels: map[string]string{
+				"action":     "requested",
+				"branch":     "feature",
+				"baseBranch": "main",
+				"prNumber":   "42",
+			},
+		},
+		{
+			name: "check_suite webhook for multiple PRs",
+			webhook: &github.CheckSuiteEvent{
+				Action: github.String("requested"),
+				CheckSuite: &github.CheckSuite{
+					HeadBranch:   github.String("feature"),
+					PullRequests: []*github.PullRequest{testPR, testPR},
+				},
+			},
+			expectedLabels: map[string]string{
+				"action": "requested",
+				"branch": "feature",
+			},
+		},
+		{
+			name: "create webhook for a tag",
+			webhook: &github.CreateEvent{
+				Ref:     github.String("v1.0.0"),
+				RefType: github.String("tag"),
+				Sender:  testSender,
+			},
+			expectedLabels: map[string]string{
+				"sender": "krancour",
+				"tag":    "v1.0.0",
+			},
+		},
+		{
+			name: "delete webhook for a branch",
+			webhook: &github.DeleteEvent{
+				Ref:     github.String("feature"),
+				RefType: github.String("branch"),
+			},
+			expectedLabels: map[string]string{
+				"branch": "feature",
+			},
+		},
+		{
+			name: "issue_comment webhook on a PR",
+			webhook: &github.IssueCommentEvent{
+				Action: github.String("created"),
+				Issue: &github.Issue{
+					Number:           github.Int(42),
+					PullRequestLinks: &github.PullRequestLinks{},
+				},
+				Comment: &github.IssueComment{
+					AuthorAssociation: github.String("OWNER"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"action":             "created",
+				"prNumber":           "42",
+				"author_association": "OWNER",
+			},
+		},
+		{
+			name: "pull_request webhook",
+			webhook: &github.PullRequestEvent{
+				Action:      github.String("opened"),
+				Sender:      testSender,
+				PullRequest: testPR,
+			},
+			deliveryID: testDeliveryID,
+			expectedLabels: map[string]string{
+				"deliveryID":         testDeliveryID,
+				"action":             "opened",
+				"sender":             "krancour",
+				"branch":             "feature",
+				"baseBranch":         "main",
+				"prNumber":           "42",
+				"author_association": "CONTRIBUTOR",
+			},
+		},
+		{
+			name: "pull_request webhook; labeled",
+			webhook: &github.PullRequestEvent{
+				Action:      github.String("labeled"),
+				PullRequest: testPR,
+				Label: &github.Label{
+					Name: github.String("deploy-preview"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"action":             "labeled",
+				"branch":             "feature",
+				"baseBranch":         "main",
+				"prNumber":           "42",
+				"author_association": "CONTRIBUTOR",
+				"label":              "deploy-preview",
+			},
+		},
+		{
+			name: "pull_request_review webhook",
+			webhook: &github.PullRequestReviewEvent{
+				Action:      github.String("submitted"),
+				PullRequest: testPR,
+				Review: &github.PullRequestReview{
+					AuthorAssociation: github.String("MEMBER"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"action":             "submitted",
+				"branch":             "feature",
+				"baseBranch":         "main",
+				"prNumber":           "42",
+				"author_association": "MEMBER",
+			},
+		},
+		{
+			name: "push webhook for a branch",
+			webhook: &github.PushEvent{
+				Ref:    github.String("refs/heads/main"),
+				Sender: testSender,
+			},
+			expectedLabels: map[string]string{
+				"sender": "krancour",
+				"branch": "main",
+			},
+		},
+		{
+			name: "push webhook for a tag",
+			webhook: &github.PushEvent{
+				Ref: github.String("refs/tags/v1.0.0"),
+			},
+			expectedLabels: map[string]string{
+				"tag": "v1.0.0",
+			},
+		},
+		{
+			name: "release webhook",
+			webhook: &github.ReleaseEvent{
+				Action: github.String("published"),
+				Release: &github.RepositoryRelease{
+					TagName: github.String("v1.0.0"),
+				},
+			},
+			expectedLabels: map[string]string{
+				"action": "published",
+				"tag":    "v1.0.0",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedLabels,
+				getRichLabels(testCase.webhook, testCase.deliveryID),
+			)
+		})
+	}
+}
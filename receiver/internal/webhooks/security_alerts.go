@@ -0,0 +1,518 @@
+package webhooks
+
+import "github.com/google/go-github/v33/github"
+
+// codeScanningAlertEvent represents a code_scanning_alert webhook, which the
+// version of go-github we depend on does not support.
+type codeScanningAlertEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Alert        *codeScanningAlert   `json:"alert,omitempty"`
+	Ref          *string              `json:"ref,omitempty"`
+	CommitOID    *string              `json:"commit_oid,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// codeScanningAlert represents an alert raised by a code scanning tool.
+type codeScanningAlert struct {
+	Number *int              `json:"number,omitempty"`
+	State  *string           `json:"state,omitempty"`
+	Rule   *codeScanningRule `json:"rule,omitempty"`
+}
+
+// codeScanningRule represents the code scanning rule that raised an alert.
+type codeScanningRule struct {
+	ID          *string `json:"id,omitempty"`
+	Severity    *string `json:"severity,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// SecuritySeverityLevel is only present for rules that identify security
+	// issues, as opposed to, for instance, style issues.
+	SecuritySeverityLevel *string `json:"security_severity_level,omitempty"`
+}
+
+// secretScanningAlertEvent represents a secret_scanning_alert webhook, which
+// the version of go-github we depend on does not support.
+type secretScanningAlertEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Alert        *secretScanningAlert `json:"alert,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// secretScanningAlert represents an alert raised by secret scanning.
+type secretScanningAlert struct {
+	Number                *int    `json:"number,omitempty"`
+	State                 *string `json:"state,omitempty"`
+	SecretType            *string `json:"secret_type,omitempty"`
+	SecretTypeDisplayName *string `json:"secret_type_display_name,omitempty"`
+}
+
+// dependabotAlertEvent represents a dependabot_alert webhook, which the
+// version of go-github we depend on does not support.
+type dependabotAlertEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Alert        *dependabotAlert     `json:"alert,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// dependabotAlert represents an alert raised by Dependabot for a vulnerable
+// dependency.
+type dependabotAlert struct {
+	Number           *int              `json:"number,omitempty"`
+	State            *string           `json:"state,omitempty"`
+	Dependency       *dependabotDep    `json:"dependency,omitempty"`
+	SecurityAdvisory *securityAdvisory `json:"security_advisory,omitempty"`
+}
+
+// dependabotDep represents the vulnerable dependency that a Dependabot alert
+// pertains to.
+type dependabotDep struct {
+	Package *advisoryPackage `json:"package,omitempty"`
+}
+
+// repositoryVulnerabilityAlertEvent represents a
+// repository_vulnerability_alert webhook. It is used in lieu of
+// github.RepositoryVulnerabilityAlertEvent, which lacks the severity, state,
+// sender, and installation properties.
+type repositoryVulnerabilityAlertEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Alert        *vulnerabilityAlert  `json:"alert,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// vulnerabilityAlert represents an alert for a vulnerable dependency.
+type vulnerabilityAlert struct {
+	Number              *int    `json:"number,omitempty"`
+	State               *string `json:"state,omitempty"`
+	Severity            *string `json:"severity,omitempty"`
+	AffectedPackageName *string `json:"affected_package_name,omitempty"`
+	AffectedRange       *string `json:"affected_range,omitempty"`
+}
+
+// securityAdvisoryEvent represents a security_advisory webhook, which the
+// version of go-github we depend on does not support. Security advisories are
+// published to the GitHub Advisory Database and do not pertain to any one
+// repository.
+type securityAdvisoryEvent struct {
+	Action           *string              `json:"action,omitempty"`
+	SecurityAdvisory *securityAdvisory    `json:"security_advisory,omitempty"`
+	Sender           *github.User         `json:"sender,omitempty"`
+	Installation     *github.Installation `json:"installation,omitempty"`
+}
+
+// securityAdvisory represents an advisory in the GitHub Advisory Database.
+type securityAdvisory struct {
+	GHSAID          *string                  `json:"ghsa_id,omitempty"`
+	CVEID           *string                  `json:"cve_id,omitempty"`
+	Summary         *string                  `json:"summary,omitempty"`
+	Severity        *string                  `json:"severity,omitempty"`
+	Vulnerabilities []*advisoryVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// advisoryVulnerability represents a vulnerable package and range of versions
+// that a security advisory pertains to.
+type advisoryVulnerability struct {
+	Package *advisoryPackage `json:"package,omitempty"`
+}
+
+// advisoryPackage represents a package in some ecosystem (e.g. npm).
+type advisoryPackage struct {
+	Ecosystem *string `json:"ecosystem,omitempty"`
+	Name      *string `json:"name,omitempty"`
+}
+
+// getSecurityAlertLabels returns labels conveying the severity of an alert or
+// advisory, the rule or package it pertains to, and its state. Any of these
+// that are unknown are omitted.
+func getSecurityAlertLabels(
+	severity string,
+	rule string,
+	pkg string,
+	state string,
+) map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{
+		"severity": severity,
+		"rule":     rule,
+		"package":  pkg,
+		"state":    state,
+	} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+func (c *codeScanningAlertEvent) GetAction() string {
+	if c == nil || c.Action == nil {
+		return ""
+	}
+	return *c.Action
+}
+
+func (c *codeScanningAlertEvent) GetAlert() *codeScanningAlert {
+	if c == nil {
+		return nil
+	}
+	return c.Alert
+}
+
+func (c *codeScanningAlertEvent) GetRef() string {
+	if c == nil || c.Ref == nil {
+		return ""
+	}
+	return *c.Ref
+}
+
+func (c *codeScanningAlertEvent) GetCommitOID() string {
+	if c == nil || c.CommitOID == nil {
+		return ""
+	}
+	return *c.CommitOID
+}
+
+func (c *codeScanningAlertEvent) GetRepo() *github.Repository {
+	if c == nil {
+		return nil
+	}
+	return c.Repo
+}
+
+func (c *codeScanningAlertEvent) GetSender() *github.User {
+	if c == nil {
+		return nil
+	}
+	return c.Sender
+}
+
+func (c *codeScanningAlertEvent) GetInstallation() *github.Installation {
+	if c == nil {
+		return nil
+	}
+	return c.Installation
+}
+
+func (c *codeScanningAlert) GetNumber() int {
+	if c == nil || c.Number == nil {
+		return 0
+	}
+	return *c.Number
+}
+
+func (c *codeScanningAlert) GetState() string {
+	if c == nil || c.State == nil {
+		return ""
+	}
+	return *c.State
+}
+
+func (c *codeScanningAlert) GetRule() *codeScanningRule {
+	if c == nil {
+		return nil
+	}
+	return c.Rule
+}
+
+func (c *codeScanningRule) GetID() string {
+	if c == nil || c.ID == nil {
+		return ""
+	}
+	return *c.ID
+}
+
+// GetSeverity returns the rule's security severity level, if it has one, and
+// otherwise its (non-security) severity.
+func (c *codeScanningRule) GetSeverity() string {
+	if c == nil {
+		return ""
+	}
+	if c.SecuritySeverityLevel != nil && *c.SecuritySeverityLevel != "" {
+		return *c.SecuritySeverityLevel
+	}
+	if c.Severity == nil {
+		return ""
+	}
+	return *c.Severity
+}
+
+func (c *codeScanningRule) GetDescription() string {
+	if c == nil || c.Description == nil {
+		return ""
+	}
+	return *c.Description
+}
+
+func (s *secretScanningAlertEvent) GetAction() string {
+	if s == nil || s.Action == nil {
+		return ""
+	}
+	return *s.Action
+}
+
+func (s *secretScanningAlertEvent) GetAlert() *secretScanningAlert {
+	if s == nil {
+		return nil
+	}
+	return s.Alert
+}
+
+func (s *secretScanningAlertEvent) GetRepo() *github.Repository {
+	if s == nil {
+		return nil
+	}
+	return s.Repo
+}
+
+func (s *secretScanningAlertEvent) GetSender() *github.User {
+	if s == nil {
+		return nil
+	}
+	return s.Sender
+}
+
+func (s *secretScanningAlertEvent) GetInstallation() *github.Installation {
+	if s == nil {
+		return nil
+	}
+	return s.Installation
+}
+
+func (s *secretScanningAlert) GetNumber() int {
+	if s == nil || s.Number == nil {
+		return 0
+	}
+	return *s.Number
+}
+
+func (s *secretScanningAlert) GetState() string {
+	if s == nil || s.State == nil {
+		return ""
+	}
+	return *s.State
+}
+
+func (s *secretScanningAlert) GetSecretType() string {
+	if s == nil || s.SecretType == nil {
+		return ""
+	}
+	return *s.SecretType
+}
+
+func (s *secretScanningAlert) GetSecretTypeDisplayName() string {
+	if s == nil || s.SecretTypeDisplayName == nil {
+		return ""
+	}
+	return *s.SecretTypeDisplayName
+}
+
+func (d *dependabotAlertEvent) GetAction() string {
+	if d == nil || d.Action == nil {
+		return ""
+	}
+	return *d.Action
+}
+
+func (d *dependabotAlertEvent) GetAlert() *dependabotAlert {
+	if d == nil {
+		return nil
+	}
+	return d.Alert
+}
+
+func (d *dependabotAlertEvent) GetRepo() *github.Repository {
+	if d == nil {
+		return nil
+	}
+	return d.Repo
+}
+
+func (d *dependabotAlertEvent) GetSender() *github.User {
+	if d == nil {
+		return nil
+	}
+	return d.Sender
+}
+
+func (d *dependabotAlertEvent) GetInstallation() *github.Installation {
+	if d == nil {
+		return nil
+	}
+	return d.Installation
+}
+
+func (d *dependabotAlert) GetNumber() int {
+	if d == nil || d.Number == nil {
+		return 0
+	}
+	return *d.Number
+}
+
+func (d *dependabotAlert) GetState() string {
+	if d == nil || d.State == nil {
+		return ""
+	}
+	return *d.State
+}
+
+func (d *dependabotAlert) GetDependency() *dependabotDep {
+	if d == nil {
+		return nil
+	}
+	return d.Dependency
+}
+
+func (d *dependabotAlert) GetSecurityAdvisory() *securityAdvisory {
+	if d == nil {
+		return nil
+	}
+	return d.SecurityAdvisory
+}
+
+func (d *dependabotDep) GetPackage() *advisoryPackage {
+	if d == nil {
+		return nil
+	}
+	return d.Package
+}
+
+func (r *repositoryVulnerabilityAlertEvent) GetAction() string {
+	if r == nil || r.Action == nil {
+		return ""
+	}
+	return *r.Action
+}
+
+func (r *repositoryVulnerabilityAlertEvent) GetAlert() *vulnerabilityAlert {
+	if r == nil {
+		return nil
+	}
+	return r.Alert
+}
+
+func (r *repositoryVulnerabilityAlertEvent) GetRepo() *github.Repository {
+	if r == nil {
+		return nil
+	}
+	return r.Repo
+}
+
+func (r *repositoryVulnerabilityAlertEvent) GetSender() *github.User {
+	if r == nil {
+		return nil
+	}
+	return r.Sender
+}
+
+func (r *repositoryVulnerabilityAlertEvent) GetInstallation() *github.Installation {
+	if r == nil {
+		return nil
+	}
+	return r.Installation
+}
+
+func (v *vulnerabilityAlert) GetState() string {
+	if v == nil || v.State == nil {
+		return ""
+	}
+	return *v.State
+}
+
+func (v *vulnerabilityAlert) GetSeverity() string {
+	if v == nil || v.Severity == nil {
+		return ""
+	}
+	return *v.Severity
+}
+
+func (v *vulnerabilityAlert) GetAffectedPackageName() string {
+	if v == nil || v.AffectedPackageName == nil {
+		return ""
+	}
+	return *v.AffectedPackageName
+}
+
+func (v *vulnerabilityAlert) GetAffectedRange() string {
+	if v == nil || v.AffectedRange == nil {
+		return ""
+	}
+	return *v.AffectedRange
+}
+
+func (s *securityAdvisoryEvent) GetAction() string {
+	if s == nil || s.Action == nil {
+		return ""
+	}
+	return *s.Action
+}
+
+func (s *securityAdvisoryEvent) GetSecurityAdvisory() *securityAdvisory {
+	if s == nil {
+		return nil
+	}
+	return s.SecurityAdvisory
+}
+
+func (s *securityAdvisoryEvent) GetSender() *github.User {
+	if s == nil {
+		return nil
+	}
+	return s.Sender
+}
+
+func (s *securityAdvisoryEvent) GetInstallation() *github.Installation {
+	if s == nil {
+		return nil
+	}
+	return s.Installation
+}
+
+func (s *securityAdvisory) GetGHSAID() string {
+	if s == nil || s.GHSAID == nil {
+		return ""
+	}
+	return *s.GHSAID
+}
+
+func (s *securityAdvisory) GetSummary() string {
+	if s == nil || s.Summary == nil {
+		return ""
+	}
+	return *s.Summary
+}
+
+func (s *securityAdvisory) GetSeverity() string {
+	if s == nil || s.Severity == nil {
+		return ""
+	}
+	return *s.Severity
+}
+
+// GetPackage returns the package the advisory pertains to if, and only if, it
+// pertains to exactly one package. Otherwise, nil is returned.
+func (s *securityAdvisory) GetPackage() *advisoryPackage {
+	if s == nil || len(s.Vulnerabilities) != 1 {
+		return nil
+	}
+	return s.Vulnerabilities[0].GetPackage()
+}
+
+func (a *advisoryVulnerability) GetPackage() *advisoryPackage {
+	if a == nil {
+		return nil
+	}
+	return a.Package
+}
+
+func (a *advisoryPackage) GetName() string {
+	if a == nil || a.Name == nil {
+		return ""
+	}
+	return *a.Name
+}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecurityAlertLabels(t *testing.T) {
+	require.Equal(
+		t,
+		map[string]string{
+			"severity": "high",
+			"package":  "lodash",
+		},
+		getSecurityAlertLabels("high", "", "lodash", ""),
+	)
+}
+
+func TestCodeScanningRuleGetSeverity(t *testing.T) {
+	testCases := []struct {
+		name             string
+		rule             *codeScanningRule
+		expectedSeverity string
+	}{
+		{
+			name:             "nil rule",
+			expectedSeverity: "",
+		},
+		{
+			name: "rule without security severity level",
+			rule: &codeScanningRule{
+				Severity: github.String("warning"),
+			},
+			expectedSeverity: "warning",
+		},
+		{
+			name: "rule with security severity level",
+			rule: &codeScanningRule{
+				Severity:              github.String("error"),
+				SecuritySeverityLevel: github.String("critical"),
+			},
+			expectedSeverity: "critical",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedSeverity,
+				testCase.rule.GetSeverity(),
+			)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+)
+
+// SenderFilter describes senders whose webhooks should be ignored. A webhook is
+// ignored if it matches any of the filter's EventTypes and its sender matches
+// any of the filter's sender criteria.
+type SenderFilter struct {
+	// Name is a human-friendly name for the filter. It is used only for logging
+	// which filter caused a webhook to be ignored.
+	Name string `json:"name"`
+	// EventTypes optionally enumerates glob patterns that select the types of
+	// webhooks to which this filter applies. Patterns are matched against the
+	// webhook type or, for webhooks having an action, the webhook type and
+	// action, separated by a colon (e.g. "pull_request:opened"). A pattern
+	// such as "pull_request:*" therefore selects all pull_request webhooks. If
+	// no patterns are specified, the filter applies to all webhooks.
+	EventTypes []string `json:"eventTypes,omitempty"`
+	// Logins enumerates the logins of senders to be ignored.
+	Logins []string `json:"logins,omitempty"`
+	// Bots indicates whether all senders of type "Bot" should be ignored.
+	Bots bool `json:"bots,omitempty"`
+	// AppSlugs enumerates the slugs of GitHub Apps whose webhooks should be
+	// ignored. A sender is regarded as a GitHub App having the slug <slug> if
+	// its login is <slug>[bot].
+	AppSlugs []string `json:"appSlugs,omitempty"`
+	// AppIDs enumerates the IDs of GitHub Apps whose webhooks should be ignored.
+	// Since webhooks do not identify the App ID of a sender, this only applies
+	// to webhooks for check runs or check suites sent by the Apps they belong
+	// to.
+	AppIDs []int64 `json:"appIDs,omitempty"`
+}
+
+// Validate returns an error if the SenderFilter is invalid.
+func (s SenderFilter) Validate() error {
+	if s.Name == "" {
+		return errors.New("sender filter has no name")
+	}
+	for _, pattern := range s.EventTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(
+				err,
+				"sender filter %q has invalid event type pattern %q",
+				s.Name,
+				pattern,
+			)
+		}
+	}
+	if len(s.Logins) == 0 && !s.Bots && len(s.AppSlugs) == 0 &&
+		len(s.AppIDs) == 0 {
+		return errors.Errorf(
+			"sender filter %q does not specify any senders to ignore",
+			s.Name,
+		)
+	}
+	return nil
+}
+
+// appliesTo returns a boolean indicating whether the SenderFilter applies to
+// webhooks of the specified type (e.g. "pull_request:opened").
+func (s SenderFilter) appliesTo(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, pattern := range s.EventTypes {
+		// Patterns were validated at startup, so we can ignore the error.
+		if matched, _ := path.Match(pattern, eventType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns a boolean indicating whether the specified sender matches
+// any of the SenderFilter's criteria.
+func (s SenderFilter) matches(sender webhookSender) bool {
+	for _, login := range s.Logins {
+		if strings.EqualFold(login, sender.login) {
+			return true
+		}
+	}
+	if s.Bots && sender.isBot {
+		return true
+	}
+	for _, slug := range s.AppSlugs {
+		if sender.appSlug != "" && strings.EqualFold(slug, sender.appSlug) {
+			return true
+		}
+	}
+	for _, id := range s.AppIDs {
+		if sender.appID != 0 && id == sender.appID {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSender encapsulates everything that is known about the sender of a
+// webhook.
+type webhookSender struct {
+	login   string
+	isBot   bool
+	appSlug string
+	appID   int64
+}
+
+// getWebhookSender returns everything that can be determined about the sender
+// of the specified webhook. Only the sender itself is considered. In
+// particular, the App that a check run or check suite belongs to is regarded
+// as the sender only if the sender is that App's bot user.
+func getWebhookSender(webhook interface{}) webhookSender {
+	var sender webhookSender
+	if w, ok := webhook.(senderGetter); ok {
+		sender.login = w.GetSender().GetLogin()
+		sender.isBot = w.GetSender().GetType() == "Bot"
+		sender.appSlug = strings.TrimSuffix(sender.login, "[bot]")
+		if sender.appSlug == sender.login {
+			sender.appSlug = ""
+		}
+	}
+	if sender.appSlug == "" {
+		return sender
+	}
+	// Webhooks do not identify the App ID of a sender, but if the sender is the
+	// App that a check run or check suite belongs to, that App's ID is known.
+	if app := getCheckApp(webhook); app != nil &&
+		strings.EqualFold(app.GetSlug(), sender.appSlug) {
+		sender.appID = app.GetID()
+	}
+	return sender
+}
+
+// getCheckApp returns the GitHub App that the check run or check suite that the
+// specified webhook pertains to belongs to. If the webhook does not pertain to
+// a check run or check suite, nil is returned.
+func getCheckApp(webhook interface{}) *github.App {
+	switch w := webhook.(type) {
+	case *github.CheckRunEvent:
+		return w.GetCheckRun().GetApp()
+	case *github.CheckSuiteEvent:
+		return w.GetCheckSuite().GetApp()
+	}
+	return nil
+}
+
+// getWebhookEventType returns the type of the specified webhook or, if the
+// webhook has an action, the type and action, separated by a colon.
+func getWebhookEventType(webhookType string, webhook interface{}) string {
+	if w, ok := webhook.(actionGetter); ok && w.GetAction() != "" {
+		return fmt.Sprintf("%s:%s", webhookType, w.GetAction())
+	}
+	return webhookType
+}
+
+// isIgnoredSender returns a boolean indicating whether the specified webhook
+// should be ignored on account of its sender. Check run webhooks for check runs
+// belonging to the GitHub App that received the webhook (i.e. check runs
+// created by this gateway) are always ignored, except when someone requested a
+// re-run or a requested action, to avoid feedback loops. Beyond that, webhooks
+// are ignored if any configured SenderFilter matches. Webhooks for check runs
+// and check suites belonging to the GitHub App that received the webhook are
+// exempt from sender filters, since they are frequently the result of this
+// gateway's own check suite forwarding, which must not be defeated by filters
+// such as one ignoring all bots.
+func (s *service) isIgnoredSender(
+	appID int64,
+	webhookType string,
+	webhook interface{},
+) bool {
+	if w, ok := webhook.(*github.CheckRunEvent); ok &&
+		w.GetCheckRun().GetApp().GetID() == appID {
+		switch w.GetAction() {
+		case "rerequested", "requested_action":
+		default:
+			log.Printf(
+				"ignoring %s webhook for check run %d created by this gateway",
+				getWebhookEventType(webhookType, webhook),
+				w.GetCheckRun().GetID(),
+			)
+			return true
+		}
+	}
+	if len(s.config.SenderFilters) == 0 {
+		return false
+	}
+	if app := getCheckApp(webhook); app != nil && app.GetID() == appID {
+		return false
+	}
+	eventType := getWebhookEventType(webhookType, webhook)
+	sender := getWebhookSender(webhook)
+	for _, filter := range s.config.SenderFilters {
+		if filter.appliesTo(eventType) && filter.matches(sender) {
+			log.Printf(
+				"ignoring %s webhook from %q; sender filter %q matches",
+				eventType,
+				sender.login,
+				filter.Name,
+			)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderFilterValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		filter     SenderFilter
+		assertions func(error)
+	}{
+		{
+			name:   "no name",
+			filter: SenderFilter{},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "has no name")
+			},
+		},
+		{
+			name: "invalid event type pattern",
+			filter: SenderFilter{
+				Name:       "foo",
+				EventTypes: []string{"pull_request:["},
+				Bots:       true,
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid event type pattern")
+			},
+		},
+		{
+			name: "no senders",
+			filter: SenderFilter{
+				Name:       "foo",
+				EventTypes: []string{"pull_request:*"},
+			},
+			assertions: func(err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"does not specify any senders to ignore",
+				)
+			},
+		},
+		{
+			name: "valid",
+			filter: SenderFilter{
+				Name:     "foo",
+				AppSlugs: []string{"dependabot"},
+			},
+			assertions: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(testCase.filter.Validate())
+		})
+	}
+}
+
+func TestGetWebhookSender(t *testing.T) {
+	testCases := []struct {
+		name           string
+		webhook        interface{}
+		expectedSender webhookSender
+	}{
+		{
+			name: "user",
+			webhook: &github.PullRequestEvent{
+				Sender: &github.User{
+					Login: github.String("krancour"),
+					Type:  github.String("User"),
+				},
+			},
+			expectedSender: webhookSender{
+				login: "krancour",
+			},
+		},
+		{
+			name: "bot",
+			webhook: &github.PullRequestEvent{
+				Sender: &github.User{
+					Login: github.String("dependabot[bot]"),
+					Type:  github.String("Bot"),
+				},
+			},
+			expectedSender: webhookSender{
+				login:   "dependabot[bot]",
+				isBot:   true,
+				appSlug: "dependabot",
+			},
+		},
+		{
+			name: "user re-running a check run belonging to an app",
+			webhook: &github.CheckRunEvent{
+				Sender: &github.User{
+					Login: github.String("krancour"),
+					Type:  github.String("User"),
+				},
+				CheckRun: &github.CheckRun{
+					App: &github.App{
+						ID:   github.Int64(42),
+						Slug: github.String("brigade"),
+					},
+				},
+			},
+			expectedSender: webhookSender{
+				login: "krancour",
+			},
+		},
+		{
+			name: "app sending a webhook for its own check suite",
+			webhook: &github.CheckSuiteEvent{
+				Sender: &github.User{
+					Login: github.String("brigade[bot]"),
+					Type:  github.String("Bot"),
+				},
+				CheckSuite: &github.CheckSuite{
+					App: &github.App{
+						ID:   github.Int64(42),
+						Slug: github.String("brigade"),
+					},
+				},
+			},
+			expectedSender: webhookSender{
+				login:   "brigade[bot]",
+				isBot:   true,
+				appSlug: "brigade",
+				appID:   42,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedSender,
+				getWebhookSender(testCase.webhook),
+			)
+		})
+	}
+}
+
+func TestIsIgnoredSender(t *testing.T) {
+	const testAppID = 42
+	testDependabot := &github.User{
+		Login: github.String("dependabot[bot]"),
+		Type:  github.String("Bot"),
+	}
+	testFilters := []SenderFilter{
+		{
+			Name:       "dependabot-prs",
+			EventTypes: []string{"pull_request:*"},
+			AppSlugs:   []string{"dependabot"},
+		},
+		{
+			Name:   "renovate",
+			Logins: []string{"renovate[bot]"},
+		},
+		{
+			Name:   "other-ci",
+			AppIDs: []int64{1234},
+		},
+	}
+	testCases := []struct {
+		name            string
+		webhookType     string
+		webhook         interface{}
+		expectedIgnored bool
+	}{
+		{
+			name:        "own check run completed",
+			webhookType: "check_run",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("completed"),
+				CheckRun: &github.CheckRun{
+					App: &github.App{ID: github.Int64(testAppID)},
+				},
+			},
+			expectedIgnored: true,
+		},
+		{
+			name:        "own check run rerequested",
+			webhookType: "check_run",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("rerequested"),
+				CheckRun: &github.CheckRun{
+					App: &github.App{ID: github.Int64(testAppID)},
+				},
+			},
+			expectedIgnored: false,
+		},
+		{
+			name:        "own check run requested action",
+			webhookType: "check_run",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("requested_action"),
+				CheckRun: &github.CheckRun{
+					App: &github.App{ID: github.Int64(testAppID)},
+				},
+			},
+			expectedIgnored: false,
+		},
+		{
+			name:        "other app's check run completed by that app",
+			webhookType: "check_run",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("completed"),
+				Sender: &github.User{
+					Login: github.String("other-ci[bot]"),
+					Type:  github.String("Bot"),
+				},
+				CheckRun: &github.CheckRun{
+					App: &github.App{
+						ID:   github.Int64(1234),
+						Slug: github.String("other-ci"),
+					},
+				},
+			},
+			expectedIgnored: true,
+		},
+		{
+			name:        "other app's check run rerequested by a user",
+			webhookType: "check_run",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("rerequested"),
+				Sender: &github.User{
+					Login: github.String("krancour"),
+					Type:  github.String("User"),
+				},
+				CheckRun: &github.CheckRun{
+					App: &github.App{
+						ID:   github.Int64(1234),
+						Slug: github.String("other-ci"),
+					},
+				},
+			},
+			expectedIgnored: false,
+		},
+		{
+			name:        "own check suite rerequested by a filtered bot",
+			webhookType: "check_suite",
+			webhook: &github.CheckSuiteEvent{
+				Action: github.String("rerequested"),
+				Sender: &github.User{
+					Login: github.String("renovate[bot]"),
+					Type:  github.String("Bot"),
+				},
+				CheckSuite: &github.CheckSuite{
+					App: &github.App{ID: github.Int64(testAppID)},
+				},
+			},
+			expectedIgnored: false,
+		},
+		{
+			name:        "filtered app's PR",
+			webhookType: "pull_request",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Sender: testDependabot,
+			},
+			expectedIgnored: true,
+		},
+		{
+			name:        "filtered app's push",
+			webhookType: "push",
+			webhook: &github.PushEvent{
+				Sender: testDependabot,
+			},
+			expectedIgnored: false,
+		},
+		{
+			name:        "filtered login",
+			webhookType: "push",
+			webhook: &github.PushEvent{
+				Sender: &github.User{
+					Login: github.String("renovate[bot]"),
+					Type:  github.String("Bot"),
+				},
+			},
+			expectedIgnored: true,
+		},
+		{
+			name:        "unfiltered user",
+			webhookType: "pull_request",
+			webhook: &github.PullRequestEvent{
+				Action: github.String("opened"),
+				Sender: &github.User{
+					Login: github.String("krancour"),
+					Type:  github.String("User"),
+				},
+			},
+			expectedIgnored: false,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: ServiceConfig{
+					SenderFilters: testFilters,
+				},
+			}
+			require.Equal(
+				t,
+				testCase.expectedIgnored,
+				s.isIgnoredSender(testAppID, testCase.webhookType, testCase.webhook),
+			)
+		})
+	}
+}
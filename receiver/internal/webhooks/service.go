@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade-github-gateway/payloads"
 	"github.com/brigadecore/brigade/sdk/v3"
 	"github.com/google/go-github/v33/github"
 	"github.com/pkg/errors"
@@ -19,6 +20,67 @@ var (
 	tagRefRegex    = regexp.MustCompile("refs/tags/(.+)")
 )
 
+// PRCloneURLSource represents which repository involved in a PR should be
+// cloned when handling events pertaining to that PR.
+type PRCloneURLSource string
+
+const (
+	// PRCloneURLSourceBase indicates that the repository into which a PR would
+	// be merged should be cloned. This is the default.
+	PRCloneURLSourceBase PRCloneURLSource = "base"
+	// PRCloneURLSourceHead indicates that the repository from which a PR
+	// originates (possibly a fork) should be cloned.
+	PRCloneURLSourceHead PRCloneURLSource = "head"
+)
+
+// PRCloneURLProtocol represents which protocol Brigade should use to clone the
+// repository involved in a PR.
+type PRCloneURLProtocol string
+
+const (
+	// PRCloneURLProtocolHTTPS indicates that repositories should be cloned over
+	// HTTPS. Since cloning a private repository over HTTPS requires credentials
+	// Brigade cannot be assumed to have, no clone URL is specified for private
+	// repositories, so that Brigade falls back to the project's own. This is
+	// the default.
+	PRCloneURLProtocolHTTPS PRCloneURLProtocol = "https"
+	// PRCloneURLProtocolSSH indicates that repositories should be cloned over
+	// SSH. This requires Brigade to be configured with an SSH key.
+	PRCloneURLProtocolSSH PRCloneURLProtocol = "ssh"
+)
+
+// PayloadFormat represents the format of emitted events' payloads.
+type PayloadFormat string
+
+const (
+	// PayloadFormatGitHub indicates that each emitted event's payload should be
+	// the original webhook's JSON payload, without any modification. This is the
+	// default.
+	PayloadFormatGitHub PayloadFormat = "github"
+	// PayloadFormatCloudEvents indicates that each emitted event's payload
+	// should be a CloudEvents 1.0 JSON envelope having the original webhook's
+	// JSON payload as its data.
+	PayloadFormatCloudEvents PayloadFormat = "cloudevents"
+)
+
+// NormalizedPayloadMode represents whether and how a normalized payload should
+// be attached to emitted events.
+type NormalizedPayloadMode string
+
+const (
+	// NormalizedPayloadModeDisabled indicates that no normalized payload should
+	// be attached to emitted events. This is the default.
+	NormalizedPayloadModeDisabled NormalizedPayloadMode = "disabled"
+	// NormalizedPayloadModePayload indicates that each emitted event's payload
+	// should be a normalized payload instead of the original webhook's JSON
+	// payload.
+	NormalizedPayloadModePayload NormalizedPayloadMode = "payload"
+	// NormalizedPayloadModeSidecar indicates that a normalized payload should be
+	// added to each emitted event's payload as a sidecar field alongside the
+	// original webhook's JSON payload.
+	NormalizedPayloadModeSidecar NormalizedPayloadMode = "sidecar"
+)
+
 // ServiceConfig encapsulates configuration options for webhook-handling
 // service.
 type ServiceConfig struct {
@@ -30,23 +92,170 @@ type ServiceConfig struct {
 	// COLLABORATOR, CONTRIBUTOR, OWNER, NONE, MEMBER, FIRST_TIMER, and
 	// FIRST_TME_CONTRIBUTOR.
 	CheckSuiteAllowedAuthorAssociations []string
+	// CheckSuitePolicies enumerates per-org or per-repository overrides of the
+	// global check suite forwarding configuration. When more than one policy
+	// matches a given repository, the first one wins.
+	CheckSuitePolicies []CheckSuitePolicy
+	// CheckSuiteOKToTestLabel optionally specifies the name of a label that,
+	// when applied to a PR from a fork by a maintainer, permits check suites to
+	// be created for the PR even if its author is not otherwise allowed to
+	// trigger them. Check suites continue to be created for subsequent pushes to
+	// the PR for as long as the label remains applied and no one other than the
+	// PR's author pushes to it.
+	CheckSuiteOKToTestLabel string
+	// CheckSuiteSkipDraftPRs indicates whether PRs from forks that are still
+	// drafts should be skipped for the purposes of check suite forwarding. When
+	// true, check suites are requested for such PRs only once they are marked
+	// ready for review.
+	CheckSuiteSkipDraftPRs bool
+	// PRCloneURLSource indicates which repository involved in a PR should be
+	// cloned when handling events pertaining to that PR. Regardless of this
+	// setting, the base repository is used whenever the head repository is
+	// private or no longer exists, since it cannot be assumed that Brigade can
+	// access it.
+	PRCloneURLSource PRCloneURLSource
+	// PRCloneURLProtocol indicates which protocol Brigade should use to clone
+	// the repository involved in a PR.
+	PRCloneURLProtocol PRCloneURLProtocol
+	// PayloadFormat indicates the format of emitted events' payloads. Regardless
+	// of this setting, emitted events' types, qualifiers, and labels are the
+	// same.
+	PayloadFormat PayloadFormat
+	// NormalizedPayloadMode indicates whether and how a normalized payload,
+	// conveying commonly useful details of the webhook in a form that does not
+	// vary by webhook type, should be attached to emitted events.
+	NormalizedPayloadMode NormalizedPayloadMode
+	// PayloadStore optionally specifies a store to which the payloads of emitted
+	// events are offloaded when they exceed PayloadStoreThreshold bytes. Each
+	// such event's payload is replaced with a small JSON document referencing
+	// the offloaded payload. If unspecified, payloads are never offloaded.
+	PayloadStore payloads.Store
+	// PayloadStoreThreshold is the size, in bytes, above which the payloads of
+	// emitted events are offloaded to the PayloadStore.
+	PayloadStoreThreshold int
+	// RichLabelsEnabled indicates whether emitted events should be labeled with
+	// additional details extracted from the corresponding webhook, such as the
+	// action, sender, branch, base branch, tag, PR number, author association,
+	// and delivery ID, where applicable. These permit Brigade projects to
+	// subscribe to events in a more fine-grained manner.
+	RichLabelsEnabled bool
+	// ComponentMappings enumerates mappings of paths within repositories to
+	// named components. When any mappings apply to a repository, events
+	// resulting from push and pull_request webhooks for that repository reflect
+	// which components are affected by the changes.
+	ComponentMappings []ComponentMapping
+	// ComponentEventsEnabled indicates whether, instead of labeling events with
+	// all affected components, one event per affected component should be
+	// emitted.
+	ComponentEventsEnabled bool
+	// SkipCIMarkers enumerates markers that, in addition to "[skip ci]" and
+	// "[ci skip]", indicate CI should be skipped when found in the head commit
+	// message of a push or check suite or in the title of a PR.
+	SkipCIMarkers []string
+	// SkipCICheckRunName optionally specifies the name of a check run that
+	// should be created, with a neutral conclusion, whenever CI is skipped for
+	// a check suite. This permits branch protection rules requiring a check run
+	// by that name to be satisfied. If unspecified, no such check run is
+	// created.
+	SkipCICheckRunName string
+	// DispatchPolicies enumerates per-org or per-repository allowlists of
+	// repository_dispatch event types that may be emitted as events and of the
+	// client_payload keys that should be applied to those events as labels.
+	// repository_dispatch webhooks not permitted by any policy are ignored. When
+	// more than one policy permits a given webhook, the first one wins.
+	DispatchPolicies []DispatchPolicy
+	// CICDMappings enumerates mappings of events to additional events of
+	// particular importance to CI/CD (e.g. cd:pipeline_requested), which are
+	// emitted alongside them. These are consulted only after built-in mappings,
+	// such as the one from check_suite:requested to ci:pipeline_requested. When
+	// more than one mapping applies to a given event, the first one wins.
+	CICDMappings []CICDMapping
+	// PackageCDEnabled indicates whether, for each package:published or
+	// registry_package:published event, a cd:pipeline_requested event should be
+	// emitted as well, just as one is for each release:published event.
+	PackageCDEnabled bool
+	// SupersedeTrackedEvents indicates whether, when a tracked event (i.e. one
+	// whose results are reported upstream to GitHub) is emitted for a new commit
+	// to a PR or branch, earlier tracked events for the same PR or branch that
+	// are still pending or running should be canceled. The check runs of any
+	// jobs canceled in this manner are reported as superseded.
+	SupersedeTrackedEvents bool
+	// SenderFilters enumerates filters that select webhooks to be ignored on
+	// account of their sender. Ignored webhooks result in no events being
+	// emitted and no check suites being forwarded.
+	SenderFilters []SenderFilter
+	// WorkflowRunTriggers enumerates the names of GitHub Actions workflows that,
+	// upon completing successfully, should trigger a CI pipeline in Brigade. For
+	// each such workflow run, a ci:pipeline_requested event is emitted in
+	// addition to the workflow_run:completed event.
+	WorkflowRunTriggers []string
 }
 
 // Service is an interface for components that can handle webhooks from GitHub.
 // Implementations of this interface are transport-agnostic.
 type Service interface {
-	// Handle handles a GitHub webhook.
+	// Handle handles a GitHub webhook. The deliveryID argument is the GUID
+	// GitHub assigned to the webhook delivery, if known.
 	Handle(
 		ctx context.Context,
 		appID int64,
 		webhookType string,
+		deliveryID string,
 		payload []byte,
 	) (sdk.EventList, error)
 }
 
 type service struct {
-	eventsClient sdk.EventsClient
-	config       ServiceConfig
+	eventsClient           sdk.EventsClient
+	checkRunsClientFactory ghlib.CheckRunsClientFactory
+	config                 ServiceConfig
+	// These internal functions are overridable for testing purposes
+	requestCheckSuiteFn func(
+		ctx context.Context,
+		app ghlib.App,
+		installationID int64,
+		repoOwner string,
+		repoName string,
+		commit string,
+	) error
+	removeLabelFn func(
+		ctx context.Context,
+		app ghlib.App,
+		installationID int64,
+		repoOwner string,
+		repoName string,
+		number int,
+		label string,
+	) error
+	getAuthorAssociationFn func(
+		ctx context.Context,
+		app ghlib.App,
+		installationID int64,
+		repoOwner string,
+		repoName string,
+		login string,
+	) (string, error)
+	getPRFromIssueCommentWebhookFn func(
+		ctx context.Context,
+		app ghlib.App,
+		ice github.IssueCommentEvent,
+	) (*github.PullRequest, error)
+	listPRFilesFn func(
+		ctx context.Context,
+		app ghlib.App,
+		installationID int64,
+		repoOwner string,
+		repoName string,
+		number int,
+	) ([]string, error)
+	listPRsForCommitFn func(
+		ctx context.Context,
+		app ghlib.App,
+		installationID int64,
+		repoOwner string,
+		repoName string,
+		commit string,
+	) ([]*github.PullRequest, error)
 }
 
 // NewService returns an implementation of the Service interface for handling
@@ -55,10 +264,18 @@ func NewService(
 	eventsClient sdk.EventsClient,
 	config ServiceConfig,
 ) Service {
-	return &service{
-		eventsClient: eventsClient,
-		config:       config,
+	s := &service{
+		eventsClient:           eventsClient,
+		checkRunsClientFactory: ghlib.NewCheckRunsClientFactory(),
+		config:                 config,
 	}
+	s.requestCheckSuiteFn = s.requestCheckSuite
+	s.removeLabelFn = s.removeLabel
+	s.getAuthorAssociationFn = s.getAuthorAssociation
+	s.getPRFromIssueCommentWebhookFn = s.getPRFromIssueCommentWebhook
+	s.listPRFilesFn = s.listPRFiles
+	s.listPRsForCommitFn = s.listPRsForCommit
+	return s
 }
 
 // nolint: gocyclo
@@ -66,17 +283,35 @@ func (s *service) Handle(
 	ctx context.Context,
 	appID int64,
 	webhookType string,
+	deliveryID string,
 	payload []byte,
 ) (sdk.EventList, error) {
 	var eventsToEmit []sdk.Event
 	var eventsEmitted sdk.EventList
 
-	webhook, err := github.ParseWebHook(webhookType, payload)
+	webhook, err := parseWebHook(webhookType, payload)
 	if err != nil {
 		return eventsEmitted, errors.Wrap(err, "error unmarshaling payload")
 	}
 
-	if err = s.checkSuiteForwarding(ctx, appID, webhook); err != nil {
+	if s.isIgnoredSender(appID, webhookType, webhook) {
+		return eventsEmitted, nil
+	}
+
+	// Comments on PRs do not, by themselves, convey what code is under
+	// discussion. Both check suite forwarding and the type switch below may need
+	// to look up the PR, so they share a single lookup.
+	var getCommentPR func() (*github.PullRequest, error)
+	if ice, ok := webhook.(*github.IssueCommentEvent); ok {
+		getCommentPR = s.getCommentPRFn(ctx, appID, ice)
+	}
+
+	if err = s.checkSuiteForwarding(
+		ctx,
+		appID,
+		webhook,
+		getCommentPR,
+	); err != nil {
 		// Log the error and move on. Check suite forwarding failed, but we should
 		// still emit an event corresponding to the webhook in hand to Brigade's
 		// event bus.
@@ -91,6 +326,11 @@ func (s *service) Handle(
 		},
 	}
 
+	// Details that are only readily available within the type switch are
+	// collected here. If the service is configured to do so, these are used
+	// later to attach a normalized payload to each event.
+	normalized := normalizedPayload{}
+
 	// Most of this function is just a giant type switch that extracts relevant
 	// details from all the known GitHub webhook types, each of which is
 	// represented by its own Go type. For developer convenience, each case links
@@ -98,6 +338,32 @@ func (s *service) Handle(
 
 	switch webhook := webhook.(type) {
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#branch_protection_rule
+	//
+	// Activity related to a branch protection rule. The type of activity is
+	// specified in the action property of the payload object. For more
+	// information, see "About protected branches."
+	case *branchProtectionRuleEvent:
+		event.Type = fmt.Sprintf("branch_protection_rule:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getProtectionLabels(
+				webhook.GetRule().GetName(),
+				webhook.GetSender().GetLogin(),
+				webhook.getRemovedChecks(),
+			),
+		)
+		event.ShortTitle = fmt.Sprintf(
+			"branch protection rule: %s",
+			webhook.GetRule().GetName(),
+		)
+		event.LongTitle = event.ShortTitle
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#check_run
 	//
@@ -157,6 +423,14 @@ func (s *service) Handle(
 			Commit: webhook.GetCheckSuite().GetHeadSHA(),
 			Ref:    webhook.GetCheckSuite().GetHeadBranch(),
 		}
+		// If the check suite pertains to a PR, the head branch may only exist in a
+		// fork, but the head commit is always reachable from the repository the
+		// check suite belongs to, so that is the one we ask Brigade to clone. The
+		// payload does not carry enough information about the PR's head repository
+		// to do otherwise.
+		if len(webhook.GetCheckSuite().PullRequests) > 0 {
+			event.Git.CloneURL = s.getCloneURL(webhook.GetRepo())
+		}
 		if webhook.GetAction() == "requested" || webhook.GetAction() == "rerequested" { // nolint: lll
 			event.SourceState = &sdk.SourceState{
 				State: map[string]string{
@@ -171,6 +445,42 @@ func (s *service) Handle(
 				},
 			}
 		}
+		// A check suite can, in theory, pertain to more than one PR. We only
+		// convey a PR number when it's unambiguous.
+		if len(webhook.GetCheckSuite().PullRequests) == 1 {
+			normalized.PRNumber = webhook.GetCheckSuite().PullRequests[0].GetNumber()
+		}
+		normalized.Author =
+			webhook.GetCheckSuite().GetHeadCommit().GetAuthor().GetName()
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#code_scanning_alert
+	//
+	// Activity related to code scanning alerts in a repository. The type of
+	// activity is specified in the action property of the payload object. For
+	// more information, see "About code scanning."
+	case *codeScanningAlertEvent:
+		event.Type = fmt.Sprintf("code_scanning_alert:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getSecurityAlertLabels(
+				webhook.GetAlert().GetRule().GetSeverity(),
+				webhook.GetAlert().GetRule().GetID(),
+				"",
+				webhook.GetAlert().GetState(),
+			),
+		)
+		event.ShortTitle =
+			fmt.Sprintf("code scanning alert #%d", webhook.GetAlert().GetNumber())
+		event.LongTitle = webhook.GetAlert().GetRule().GetDescription()
+		event.Git = &sdk.GitDetails{
+			Commit: webhook.GetCommitOID(),
+			Ref:    webhook.GetRef(),
+		}
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -187,6 +497,15 @@ func (s *service) Handle(
 			Ref: webhook.GetRef(),
 		}
 		eventsToEmit = []sdk.Event{event}
+		// Emit an additional create:branch or create:tag event so that
+		// subscribers can easily distinguish between the two.
+		if refTypeEvent := getRefTypeEvent(
+			event,
+			webhook.GetRefType(),
+			webhook.GetRef(),
+		); refTypeEvent != nil {
+			eventsToEmit = append(eventsToEmit, *refTypeEvent)
+		}
 
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#delete
@@ -198,6 +517,47 @@ func (s *service) Handle(
 		event.Qualifiers = map[string]string{
 			"repo": webhook.GetRepo().GetFullName(),
 		}
+		// The deleted ref no longer exists, so we do not populate event.Git, as
+		// that would cause any attempt to clone it to fail. Instead, we convey
+		// which ref was deleted using labels.
+		event.Labels = copyLabels(
+			event.Labels,
+			getDeletedRefLabels(webhook.GetRefType(), webhook.GetRef(), ""),
+		)
+		eventsToEmit = []sdk.Event{event}
+		// Emit an additional delete:branch or delete:tag event so that
+		// subscribers can easily distinguish between the two.
+		if refTypeEvent := getRefTypeEvent(
+			event,
+			webhook.GetRefType(),
+			webhook.GetRef(),
+		); refTypeEvent != nil {
+			eventsToEmit = append(eventsToEmit, *refTypeEvent)
+		}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#dependabot_alert
+	//
+	// Activity related to Dependabot alerts for vulnerable dependencies. The type
+	// of activity is specified in the action property of the payload object. For
+	// more information, see "About Dependabot alerts."
+	case *dependabotAlertEvent:
+		event.Type = fmt.Sprintf("dependabot_alert:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getSecurityAlertLabels(
+				webhook.GetAlert().GetSecurityAdvisory().GetSeverity(),
+				"",
+				webhook.GetAlert().GetDependency().GetPackage().GetName(),
+				webhook.GetAlert().GetState(),
+			),
+		)
+		event.ShortTitle =
+			fmt.Sprintf("dependabot alert #%d", webhook.GetAlert().GetNumber())
+		event.LongTitle = webhook.GetAlert().GetSecurityAdvisory().GetSummary()
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -242,6 +602,66 @@ func (s *service) Handle(
 	// 	}
 	//  eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#discussion
+	//
+	// Activity related to a discussion. The type of activity is specified in the
+	// action property of the payload object. For more information, see "Using
+	// GitHub Discussions."
+	case *discussionEvent:
+		event.Type = fmt.Sprintf("discussion:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getDiscussionLabels(webhook.GetDiscussion()),
+		)
+		if webhook.GetAction() == "created" {
+			event.Labels = copyLabels(
+				event.Labels,
+				s.getCommandLabels(
+					webhook.GetRepo().GetFullName(),
+					webhook.GetDiscussion().GetAuthorAssociation(),
+					webhook.GetDiscussion().GetBody(),
+				),
+			)
+		}
+		event.ShortTitle =
+			fmt.Sprintf("discussion #%d", webhook.GetDiscussion().GetNumber())
+		event.LongTitle = webhook.GetDiscussion().GetTitle()
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#discussion_comment
+	//
+	// Activity related to a comment in a discussion. The type of activity is
+	// specified in the action property of the payload object. For more
+	// information, see "Using GitHub Discussions."
+	case *discussionCommentEvent:
+		event.Type = fmt.Sprintf("discussion_comment:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getDiscussionLabels(webhook.GetDiscussion()),
+		)
+		if webhook.GetAction() == "created" {
+			event.Labels = copyLabels(
+				event.Labels,
+				s.getCommandLabels(
+					webhook.GetRepo().GetFullName(),
+					webhook.GetComment().GetAuthorAssociation(),
+					webhook.GetComment().GetBody(),
+				),
+			)
+		}
+		event.ShortTitle =
+			fmt.Sprintf("discussion #%d", webhook.GetDiscussion().GetNumber())
+		event.LongTitle = webhook.GetDiscussion().GetTitle()
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#fork
 	//
@@ -327,6 +747,29 @@ func (s *service) Handle(
 			eventsToEmit = append(eventsToEmit, event)
 		}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#installation_target
+	//
+	// Activity relating to the user or organization account that a GitHub App is
+	// installed on. The type of activity is specified in the action property of
+	// the payload object.
+	case *installationTargetEvent:
+		event.Type = fmt.Sprintf("installation_target:%s", webhook.GetAction())
+		// Special handling for this webhook-- it pertains to an account rather
+		// than to any repository, so we qualify the event with the account's login
+		// instead. For the sake of consistency with other org-level events, we use
+		// the org qualifier even if the account belongs to a user.
+		event.Qualifiers = map[string]string{
+			"org": webhook.GetAccount().GetLogin(),
+		}
+		if from := webhook.GetChanges().GetLogin().GetFrom(); from != "" {
+			event.Labels = copyLabels(
+				event.Labels,
+				map[string]string{"previousLogin": from},
+			)
+		}
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#issue_comment
 	//
@@ -338,6 +781,37 @@ func (s *service) Handle(
 		event.Qualifiers = map[string]string{
 			"repo": webhook.GetRepo().GetFullName(),
 		}
+		// If the comment is on a PR, the webhook's payload does not, by itself,
+		// convey what code is under discussion, so we look up the PR to find out.
+		if webhook.GetIssue().IsPullRequest() {
+			pr, err := getCommentPR()
+			if err != nil {
+				// Log the error and move on. We should still emit an event, even if
+				// it lacks Git details.
+				log.Printf("error getting PR for issue comment: %s", err)
+			} else {
+				event.ShortTitle, event.LongTitle = getTitlesFromPR(pr)
+				event.Git = &sdk.GitDetails{
+					CloneURL: s.getCloneURLFromPR(pr),
+					Commit:   pr.GetHead().GetSHA(),
+					Ref:      fmt.Sprintf("refs/pull/%d/head", pr.GetNumber()),
+				}
+			}
+		}
+		if webhook.GetAction() == "created" {
+			event.Labels = copyLabels(
+				event.Labels,
+				s.getCommandLabels(
+					webhook.GetRepo().GetFullName(),
+					webhook.GetComment().GetAuthorAssociation(),
+					webhook.GetComment().GetBody(),
+				),
+			)
+		}
+		if webhook.GetIssue().IsPullRequest() {
+			normalized.PRNumber = webhook.GetIssue().GetNumber()
+		}
+		normalized.Author = webhook.GetComment().GetUser().GetLogin()
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -351,6 +825,7 @@ func (s *service) Handle(
 		event.Qualifiers = map[string]string{
 			"repo": webhook.GetRepo().GetFullName(),
 		}
+		normalized.Author = webhook.GetIssue().GetUser().GetLogin()
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -379,6 +854,26 @@ func (s *service) Handle(
 		}
 		eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#membership
+	//
+	// Activity related to team membership. The type of activity is specified in
+	// the action property of the payload object. For more information, see the
+	// "team members" REST API.
+	case *github.MembershipEvent:
+		event.Type = fmt.Sprintf("membership:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"org": webhook.GetOrg().GetLogin(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getOrgLabels(map[string]string{
+				"team":   webhook.GetTeam().GetSlug(),
+				"member": webhook.GetMember().GetLogin(),
+			}),
+		)
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#milestone
 	//
@@ -392,6 +887,74 @@ func (s *service) Handle(
 		}
 		eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#org_block
+	//
+	// Activity related to people being blocked in an organization. The type of
+	// activity is specified in the action property of the payload object. For
+	// more information, see the "blocking organization users" REST API.
+	case *github.OrgBlockEvent:
+		event.Type = fmt.Sprintf("org_block:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"org": webhook.GetOrganization().GetLogin(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getOrgLabels(map[string]string{
+				"blockedUser": webhook.GetBlockedUser().GetLogin(),
+			}),
+		)
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#organization
+	//
+	// Activity related to an organization and its members. The type of activity
+	// is specified in the action property of the payload object. For more
+	// information, see the "organizations" REST API.
+	case *github.OrganizationEvent:
+		event.Type = fmt.Sprintf("organization:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"org": webhook.GetOrganization().GetLogin(),
+		}
+		// For member_invited, there is no membership yet, only an invitation.
+		member := webhook.GetMembership().GetUser().GetLogin()
+		if member == "" {
+			member = webhook.GetInvitation().GetLogin()
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getOrgLabels(map[string]string{
+				"member": member,
+			}),
+		)
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#package
+	//
+	// Activity related to GitHub Packages. The type of activity is specified in
+	// the action property of the payload object. For more information, see
+	// "Introduction to GitHub Packages."
+	case *packageEvent:
+		event.Type = fmt.Sprintf("package:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels =
+			copyLabels(event.Labels, getPackageLabels(webhook.GetPackage()))
+		event.ShortTitle, event.LongTitle =
+			getTitlesFromPackage(webhook.GetPackage())
+		// The commit the package was published from, if known, permits the
+		// results of a tracked CI/CD pipeline to be reported upstream.
+		if commit :=
+			webhook.GetPackage().GetPackageVersion().GetTargetOID(); commit != "" {
+			event.Git = &sdk.GitDetails{
+				Commit: commit,
+			}
+		}
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#page_build
 	//
@@ -466,6 +1029,46 @@ func (s *service) Handle(
 		}
 		eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#merge_group
+	//
+	// Activity related to merge groups in a merge queue. The type of activity is
+	// specified in the action property of the payload object.
+	case *mergeGroupEvent:
+		event.Type = fmt.Sprintf("merge_group:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		if baseBranchSubmatches := branchRefRegex.FindStringSubmatch(
+			webhook.GetMergeGroup().GetBaseRef(),
+		); len(baseBranchSubmatches) == 2 {
+			event.ShortTitle = fmt.Sprintf("merge queue: %s", baseBranchSubmatches[1])
+			event.LongTitle = event.ShortTitle
+		}
+		event.Git = &sdk.GitDetails{
+			Commit: webhook.GetMergeGroup().GetHeadSHA(),
+			Ref:    webhook.GetMergeGroup().GetHeadRef(),
+		}
+		// Required checks must pass on the merge group's temporary branch before
+		// the queue can proceed, so, as with check suites, we track the results
+		// of any jobs created in handling this event so they can be reported
+		// upstream to GitHub.
+		if webhook.GetAction() == "checks_requested" {
+			event.SourceState = &sdk.SourceState{
+				State: map[string]string{
+					"tracking": "true",
+					"installationID": strconv.FormatInt(
+						webhook.GetInstallation().GetID(),
+						10,
+					),
+					"owner":   webhook.GetRepo().GetOwner().GetLogin(),
+					"repo":    webhook.GetRepo().GetName(),
+					"headSHA": webhook.GetMergeGroup().GetHeadSHA(),
+				},
+			}
+		}
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#pull_request
 	//
@@ -477,13 +1080,39 @@ func (s *service) Handle(
 		event.Qualifiers = map[string]string{
 			"repo": webhook.GetRepo().GetFullName(),
 		}
+		// Label the event so that projects can decide for themselves whether to do
+		// any expensive work for draft PRs.
+		event.Labels["draft"] = strconv.FormatBool(
+			webhook.GetPullRequest().GetDraft(),
+		)
 		event.ShortTitle, event.LongTitle =
 			getTitlesFromPR(webhook.GetPullRequest())
 		event.Git = &sdk.GitDetails{
-			Commit: webhook.GetPullRequest().GetHead().GetSHA(),
-			Ref:    fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
+			CloneURL: s.getCloneURLFromPR(webhook.GetPullRequest()),
+			Commit:   webhook.GetPullRequest().GetHead().GetSHA(),
+			Ref:      fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
 		}
+		normalized.PRNumber = webhook.GetPullRequest().GetNumber()
+		normalized.Author = webhook.GetPullRequest().GetUser().GetLogin()
 		eventsToEmit = []sdk.Event{event}
+		// A closed PR may have been merged or abandoned. To spare subscribers from
+		// digging into the payload to tell the difference, we emit an additional
+		// pull_request:merged event for the former case. Note that its Git details
+		// reference the merge commit and the base branch, since that is where the
+		// PR's changes now live.
+		if webhook.GetAction() == "closed" && webhook.GetPullRequest().GetMerged() {
+			mergedEvent := event
+			mergedEvent.Type = "pull_request:merged"
+			mergedEvent.Git = &sdk.GitDetails{
+				CloneURL: s.getCloneURL(webhook.GetPullRequest().GetBase().GetRepo()),
+				Commit:   webhook.GetPullRequest().GetMergeCommitSHA(),
+				Ref: fmt.Sprintf(
+					"refs/heads/%s",
+					webhook.GetPullRequest().GetBase().GetRef(),
+				),
+			}
+			eventsToEmit = append(eventsToEmit, mergedEvent)
+		}
 
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#pull_request_review
@@ -499,10 +1128,22 @@ func (s *service) Handle(
 		event.ShortTitle, event.LongTitle =
 			getTitlesFromPR(webhook.GetPullRequest())
 		event.Git = &sdk.GitDetails{
-			Commit: webhook.GetPullRequest().GetHead().GetSHA(),
-			Ref:    fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
+			CloneURL: s.getCloneURLFromPR(webhook.GetPullRequest()),
+			Commit:   webhook.GetPullRequest().GetHead().GetSHA(),
+			Ref:      fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
 		}
+		normalized.PRNumber = webhook.GetPullRequest().GetNumber()
+		normalized.Author = webhook.GetReview().GetUser().GetLogin()
 		eventsToEmit = []sdk.Event{event}
+		// Similarly, to spare subscribers from digging into the payload to find
+		// the review's state, we emit an additional pull_request_review:approved
+		// event when a review approves the PR.
+		if webhook.GetAction() == "submitted" &&
+			strings.EqualFold(webhook.GetReview().GetState(), "approved") {
+			approvedEvent := event
+			approvedEvent.Type = "pull_request_review:approved"
+			eventsToEmit = append(eventsToEmit, approvedEvent)
+		}
 
 	// nolint: lll
 	// https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#pull_request_review_comment
@@ -520,9 +1161,12 @@ func (s *service) Handle(
 		event.ShortTitle, event.LongTitle =
 			getTitlesFromPR(webhook.GetPullRequest())
 		event.Git = &sdk.GitDetails{
-			Commit: webhook.GetPullRequest().GetHead().GetSHA(),
-			Ref:    fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
+			CloneURL: s.getCloneURLFromPR(webhook.GetPullRequest()),
+			Commit:   webhook.GetPullRequest().GetHead().GetSHA(),
+			Ref:      fmt.Sprintf("refs/pull/%d/head", webhook.GetPullRequest().GetNumber()),
 		}
+		normalized.PRNumber = webhook.GetPullRequest().GetNumber()
+		normalized.Author = webhook.GetComment().GetUser().GetLogin()
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -534,6 +1178,7 @@ func (s *service) Handle(
 		event.Qualifiers = map[string]string{
 			"repo": webhook.GetRepo().GetFullName(),
 		}
+		normalized.Author = webhook.GetHeadCommit().GetAuthor().GetName()
 		event.ShortTitle, event.LongTitle = getTitlesFromPushWebhook(webhook)
 		event.Git = &sdk.GitDetails{
 			Commit: webhook.GetHeadCommit().GetID(),
@@ -541,9 +1186,63 @@ func (s *service) Handle(
 		}
 		if webhook.GetDeleted() {
 			// If this is a branch or tag deletion, emit a `push:delete` event
-			// instead and blank out event.Git.
+			// instead and blank out event.Git, since the ref no longer exists.
+			// Instead, we convey which ref was deleted and what commit it last
+			// referenced using labels.
 			event.Type = "push:delete"
 			event.Git = nil
+			var refType string
+			if branchRefRegex.MatchString(webhook.GetRef()) {
+				refType = "branch"
+			} else if tagRefRegex.MatchString(webhook.GetRef()) {
+				refType = "tag"
+			}
+			event.Labels = copyLabels(
+				event.Labels,
+				getDeletedRefLabels(refType, webhook.GetRef(), webhook.GetBefore()),
+			)
+			eventsToEmit = []sdk.Event{event}
+			break
+		}
+		eventsToEmit = []sdk.Event{event}
+		// Emit an additional push:branch or push:tag event so that subscribers
+		// can easily distinguish between the two.
+		var refTypeEvent *sdk.Event
+		if refSubmatches := branchRefRegex.FindStringSubmatch(
+			webhook.GetRef(),
+		); len(refSubmatches) == 2 {
+			refTypeEvent = getRefTypeEvent(event, "branch", refSubmatches[1])
+		} else if refSubmatches := tagRefRegex.FindStringSubmatch(
+			webhook.GetRef(),
+		); len(refSubmatches) == 2 {
+			refTypeEvent = getRefTypeEvent(event, "tag", refSubmatches[1])
+		}
+		if refTypeEvent != nil {
+			eventsToEmit = append(eventsToEmit, *refTypeEvent)
+		}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#registry_package
+	//
+	// Activity related to GitHub Packages. The type of activity is specified in
+	// the action property of the payload object. This is similar to the package
+	// webhook, but predates it.
+	case *registryPackageEvent:
+		event.Type = fmt.Sprintf("registry_package:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getPackageLabels(webhook.GetRegistryPackage()),
+		)
+		event.ShortTitle, event.LongTitle =
+			getTitlesFromPackage(webhook.GetRegistryPackage())
+		if commit :=
+			webhook.GetRegistryPackage().GetPackageVersion().GetTargetOID(); commit != "" {
+			event.Git = &sdk.GitDetails{
+				Commit: commit,
+			}
 		}
 		eventsToEmit = []sdk.Event{event}
 
@@ -561,6 +1260,7 @@ func (s *service) Handle(
 		event.Git = &sdk.GitDetails{
 			Ref: webhook.GetRelease().GetTagName(),
 		}
+		normalized.Author = webhook.GetRelease().GetAuthor().GetLogin()
 		eventsToEmit = []sdk.Event{event}
 
 	// nolint: lll
@@ -576,6 +1276,168 @@ func (s *service) Handle(
 		}
 		eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#repository_dispatch
+	//
+	// This event occurs when a GitHub App sends a POST request to the "Create a
+	// repository dispatch event" endpoint.
+	case *github.RepositoryDispatchEvent:
+		// Special handling for this webhook-- the dispatch's event_type (which
+		// appears in the action property) is chosen by whatever external system
+		// created the dispatch, so we only emit events for event types that are
+		// explicitly permitted for the repository and we namespace them so they
+		// cannot masquerade as any other kind of event.
+		policy := s.getDispatchPolicy(
+			webhook.GetRepo().GetFullName(),
+			webhook.GetAction(),
+		)
+		if policy == nil {
+			log.Printf(
+				"ignoring repository_dispatch webhook for repo %q; event type %q is "+
+					"not permitted by any dispatch policy",
+				webhook.GetRepo().GetFullName(),
+				webhook.GetAction(),
+			)
+			break
+		}
+		event.Type = getDispatchEventType(webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		// Labels from the client payload are namespaced, so they cannot collide
+		// with any already set.
+		event.Labels = copyLabels(
+			event.Labels,
+			getDispatchLabels(webhook.ClientPayload, policy.PayloadKeys),
+		)
+		event.ShortTitle = webhook.GetAction()
+		event.LongTitle = event.ShortTitle
+		if webhook.GetBranch() != "" {
+			event.Git = &sdk.GitDetails{
+				Ref: webhook.GetBranch(),
+			}
+		}
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#repository_ruleset
+	//
+	// Activity related to a repository ruleset. The type of activity is specified
+	// in the action property of the payload object. For more information, see
+	// "About rulesets."
+	case *repositoryRulesetEvent:
+		event.Type = fmt.Sprintf("repository_ruleset:%s", webhook.GetAction())
+		// Rulesets may also be defined at the org level, in which case there may
+		// be no repository to qualify the event with.
+		if webhook.GetRepo() != nil {
+			event.Qualifiers = map[string]string{
+				"repo": webhook.GetRepo().GetFullName(),
+			}
+		} else {
+			event.Qualifiers = map[string]string{
+				"org": webhook.GetOrg().GetLogin(),
+			}
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getProtectionLabels(
+				webhook.GetRepositoryRuleset().getBranchPattern(),
+				webhook.GetSender().GetLogin(),
+				webhook.getRemovedChecks(),
+			),
+		)
+		event.ShortTitle = fmt.Sprintf(
+			"ruleset: %s",
+			webhook.GetRepositoryRuleset().GetName(),
+		)
+		event.LongTitle = event.ShortTitle
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#repository_vulnerability_alert
+	//
+	// Activity related to security vulnerability alerts in a repository. The type
+	// of activity is specified in the action property of the payload object.
+	case *repositoryVulnerabilityAlertEvent:
+		event.Type = fmt.Sprintf(
+			"repository_vulnerability_alert:%s",
+			webhook.GetAction(),
+		)
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getSecurityAlertLabels(
+				webhook.GetAlert().GetSeverity(),
+				"",
+				webhook.GetAlert().GetAffectedPackageName(),
+				webhook.GetAlert().GetState(),
+			),
+		)
+		event.ShortTitle = fmt.Sprintf(
+			"vulnerability alert: %s",
+			webhook.GetAlert().GetAffectedPackageName(),
+		)
+		event.LongTitle = fmt.Sprintf(
+			"%s %s",
+			event.ShortTitle,
+			webhook.GetAlert().GetAffectedRange(),
+		)
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#secret_scanning_alert
+	//
+	// Activity related to secret scanning alerts in a repository. The type of
+	// activity is specified in the action property of the payload object. For
+	// more information, see "About secret scanning."
+	case *secretScanningAlertEvent:
+		event.Type = fmt.Sprintf("secret_scanning_alert:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		// Secret scanning alerts have no severity. The type of secret that was
+		// detected is the closest analog to a rule.
+		event.Labels = copyLabels(
+			event.Labels,
+			getSecurityAlertLabels(
+				"",
+				webhook.GetAlert().GetSecretType(),
+				"",
+				webhook.GetAlert().GetState(),
+			),
+		)
+		event.ShortTitle =
+			fmt.Sprintf("secret scanning alert #%d", webhook.GetAlert().GetNumber())
+		event.LongTitle = webhook.GetAlert().GetSecretTypeDisplayName()
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#security_advisory
+	//
+	// Activity related to a security advisory that has been reviewed by GitHub.
+	// The type of activity is specified in the action property of the payload
+	// object.
+	case *securityAdvisoryEvent:
+		event.Type = fmt.Sprintf("security_advisory:%s", webhook.GetAction())
+		// Special handling for this webhook-- security advisories are published to
+		// the GitHub Advisory Database and do not pertain to any one repository,
+		// so there is no repo qualifier. Only projects that subscribe to this
+		// event type without any qualifiers will receive these events.
+		event.Labels = copyLabels(
+			event.Labels,
+			getSecurityAlertLabels(
+				webhook.GetSecurityAdvisory().GetSeverity(),
+				"",
+				webhook.GetSecurityAdvisory().GetPackage().GetName(),
+				"",
+			),
+		)
+		event.ShortTitle = webhook.GetSecurityAdvisory().GetGHSAID()
+		event.LongTitle = webhook.GetSecurityAdvisory().GetSummary()
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// // From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#star
 	// //
@@ -608,6 +1470,29 @@ func (s *service) Handle(
 		}
 		eventsToEmit = []sdk.Event{event}
 
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#team
+	//
+	// Activity related to an organization's team. The type of activity is
+	// specified in the action property of the payload object. For more
+	// information, see the "teams" REST API.
+	case *github.TeamEvent:
+		event.Type = fmt.Sprintf("team:%s", webhook.GetAction())
+		// Even when a team is added to or removed from a repository, this is
+		// activity related to the team, so we qualify the event with the org and
+		// convey the repository using a label.
+		event.Qualifiers = map[string]string{
+			"org": webhook.GetOrg().GetLogin(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getOrgLabels(map[string]string{
+				"team": webhook.GetTeam().GetSlug(),
+				"repo": webhook.GetRepo().GetFullName(),
+			}),
+		)
+		eventsToEmit = []sdk.Event{event}
+
 	// nolint: lll
 	// From https://docs.github.com/en/github-ae@latest/developers/webhooks-and-events/webhook-events-and-payloads#team_add
 	//
@@ -637,14 +1522,193 @@ func (s *service) Handle(
 			"repo": webhook.GetRepo().GetFullName(),
 		}
 		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
+	//
+	// GitHub Actions workflow job activity has occurred. The type of activity is
+	// specified in the action property of the payload object. For more
+	// information, see the "workflow jobs" REST API.
+	case *workflowJobEvent:
+		event.Type = fmt.Sprintf("workflow_job:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getWorkflowLabels(
+				webhook.GetWorkflowJob().GetWorkflowName(),
+				webhook.GetWorkflowJob().GetConclusion(),
+			),
+		)
+		event.ShortTitle = webhook.GetWorkflowJob().GetName()
+		event.LongTitle = event.ShortTitle
+		if webhook.GetWorkflowJob().GetWorkflowName() != "" {
+			event.LongTitle = fmt.Sprintf(
+				"%s: %s",
+				webhook.GetWorkflowJob().GetWorkflowName(),
+				webhook.GetWorkflowJob().GetName(),
+			)
+		}
+		event.Git = &sdk.GitDetails{
+			Commit: webhook.GetWorkflowJob().GetHeadSHA(),
+			Ref:    webhook.GetWorkflowJob().GetHeadBranch(),
+		}
+		eventsToEmit = []sdk.Event{event}
+
+	// nolint: lll
+	// From https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_run
+	//
+	// When a GitHub Actions workflow run is requested or completed. For more
+	// information, see the "workflow runs" REST API.
+	case *workflowRunEvent:
+		event.Type = fmt.Sprintf("workflow_run:%s", webhook.GetAction())
+		event.Qualifiers = map[string]string{
+			"repo": webhook.GetRepo().GetFullName(),
+		}
+		event.Labels = copyLabels(
+			event.Labels,
+			getWorkflowLabels(
+				webhook.GetWorkflowRun().GetName(),
+				webhook.GetWorkflowRun().GetConclusion(),
+			),
+		)
+		event.ShortTitle = webhook.GetWorkflowRun().GetName()
+		event.LongTitle = fmt.Sprintf(
+			"%s #%d",
+			webhook.GetWorkflowRun().GetName(),
+			webhook.GetWorkflowRun().GetRunNumber(),
+		)
+		event.Git = &sdk.GitDetails{
+			Commit: webhook.GetWorkflowRun().GetHeadSHA(),
+			Ref:    webhook.GetWorkflowRun().GetHeadBranch(),
+		}
+		// If this workflow run is one that should trigger a CI pipeline, then
+		// getCICDEvent will derive a ci:pipeline_requested event from this one.
+		// We enable tracking so that the results of that pipeline are reported
+		// upstream to GitHub, just as they would be for a check suite.
+		if s.isWorkflowRunTrigger(event) {
+			event.SourceState = &sdk.SourceState{
+				State: map[string]string{
+					"tracking": "true",
+					"installationID": strconv.FormatInt(
+						webhook.GetInstallation().GetID(),
+						10,
+					),
+					"owner":   webhook.GetRepo().GetOwner().GetLogin(),
+					"repo":    webhook.GetRepo().GetName(),
+					"headSHA": webhook.GetWorkflowRun().GetHeadSHA(),
+				},
+			}
+		}
+		eventsToEmit = []sdk.Event{event}
+	}
+
+	if marker := s.getSkipCIMarker(getSkipCIText(webhook)); marker != "" {
+		// Label every event so that subscribers can tell CI was skipped.
+		// getCICDEvent will also take this label into account and refrain from
+		// emitting a ci:pipeline_requested event.
+		for i := range eventsToEmit {
+			eventsToEmit[i].Labels = copyLabels(
+				eventsToEmit[i].Labels,
+				map[string]string{"skipped": "true"},
+			)
+		}
+		if cse, ok := webhook.(*github.CheckSuiteEvent); ok &&
+			s.config.SkipCICheckRunName != "" &&
+			(cse.GetAction() == "requested" || cse.GetAction() == "rerequested") {
+			if err = s.createSkippedCheckRun(
+				ctx,
+				s.config.GitHubApps[appID],
+				cse.GetInstallation().GetID(),
+				cse.GetRepo().GetOwner().GetLogin(),
+				cse.GetRepo().GetName(),
+				cse.GetCheckSuite().GetHeadSHA(),
+				marker,
+			); err != nil {
+				// Log the error and move on. We should still emit events.
+				log.Printf("error creating check run for skipped CI: %s", err)
+			}
+		}
 	}
 
 	for _, event = range eventsToEmit {
-		if ciCDEvent := s.getCICDEvent(event); ciCDEvent != nil {
+		if ciCDEvent := s.getCICDEvent(webhook, event); ciCDEvent != nil {
 			eventsToEmit = append(eventsToEmit, *ciCDEvent)
 		}
 	}
 
+	if s.config.RichLabelsEnabled {
+		richLabels := getRichLabels(webhook, deliveryID)
+		for i := range eventsToEmit {
+			// Events derived from one another may share a single labels map, so we
+			// build a new one for each event. Labels already set take precedence.
+			eventsToEmit[i].Labels = copyLabels(richLabels, eventsToEmit[i].Labels)
+		}
+	}
+
+	componentMappings := s.getComponentMappings(webhook)
+	normalizedPayloadsEnabled :=
+		s.config.NormalizedPayloadMode == NormalizedPayloadModePayload ||
+			s.config.NormalizedPayloadMode == NormalizedPayloadModeSidecar
+	// Both components and normalized payloads depend on which paths were changed.
+	// For PRs, determining that requires a call to the GitHub API, so we do it
+	// once, and only when it's needed.
+	var changedPaths []string
+	if len(componentMappings) > 0 || normalizedPayloadsEnabled {
+		var pathsErr error
+		if changedPaths, pathsErr =
+			s.getChangedPaths(ctx, appID, webhook); pathsErr != nil {
+			// Log the error and move on. We should still emit events, even if we
+			// cannot determine which paths were changed.
+			log.Printf("error determining changed paths: %s", pathsErr)
+		}
+	}
+
+	if len(componentMappings) > 0 {
+		affectedComponents := getAffectedComponents(componentMappings, changedPaths)
+		// If the webhook's payload may not list every changed path, we'd rather
+		// over-report affected components than have a component miss a build.
+		if isTruncatedPush(webhook) {
+			affectedComponents = getMappedComponents(componentMappings)
+		}
+		eventsToEmit = s.applyComponents(eventsToEmit, affectedComponents)
+	}
+
+	if normalizedPayloadsEnabled {
+		if eventsToEmit, err = s.applyNormalizedPayloads(
+			webhook,
+			normalized,
+			changedPaths,
+			eventsToEmit,
+		); err != nil {
+			return eventsEmitted, err
+		}
+	}
+
+	if s.config.PayloadFormat == PayloadFormatCloudEvents {
+		if eventsToEmit, err = toCloudEvents(eventsToEmit, deliveryID); err != nil {
+			return eventsEmitted, err
+		}
+	}
+
+	if s.config.PayloadStore != nil {
+		eventsToEmit = s.offloadPayloads(ctx, eventsToEmit)
+	}
+
+	if s.config.SupersedeTrackedEvents {
+		if err = s.applySupersedingScope(
+			ctx,
+			appID,
+			webhook,
+			eventsToEmit,
+		); err != nil {
+			// Failing to determine a superseding scope must not prevent new events
+			// from being handled. They merely won't supersede earlier ones.
+			log.Printf("error determining superseding scope: %s", err)
+		}
+	}
+
 	for _, event = range eventsToEmit {
 		var events sdk.EventList
 		if events, err = s.eventsClient.Create(ctx, event, nil); err != nil {
@@ -656,9 +1720,47 @@ func (s *service) Handle(
 		eventsEmitted.Items = append(eventsEmitted.Items, events.Items...)
 	}
 
+	if s.config.SupersedeTrackedEvents {
+		s.cancelSupersededEvents(ctx, eventsToEmit)
+	}
+
 	return eventsEmitted, nil
 }
 
+// offloadPayloads offloads the payloads of the specified events to the
+// configured payload store whenever they exceed the configured threshold,
+// replacing them with references to the offloaded payloads. Events derived
+// from a single webhook often share a payload, so each distinct payload is
+// offloaded only once.
+func (s *service) offloadPayloads(
+	ctx context.Context,
+	events []sdk.Event,
+) []sdk.Event {
+	refs := map[string]string{}
+	for i, event := range events {
+		if len(event.Payload) <= s.config.PayloadStoreThreshold {
+			continue
+		}
+		ref, ok := refs[event.Payload]
+		if !ok {
+			var err error
+			if ref, err = payloads.Offload(
+				ctx,
+				s.config.PayloadStore,
+				event.Payload,
+			); err != nil {
+				// Log the error and move on. We should still emit the event, even if
+				// its payload is large.
+				log.Printf("error offloading event payload: %s", err)
+				continue
+			}
+			refs[event.Payload] = ref
+		}
+		events[i].Payload = ref
+	}
+	return events
+}
+
 // getTitlesFromPushWebhook extracts human-readable titles from a
 // github.PushEvent.
 func getTitlesFromPushWebhook(pe *github.PushEvent) (string, string) {
@@ -677,6 +1779,34 @@ func getTitlesFromPushWebhook(pe *github.PushEvent) (string, string) {
 	return shortTitle, longTitle
 }
 
+// getCloneURLFromPR returns the URL Brigade should use for cloning the
+// repository involved in the specified PR, according to the service's
+// configured PRCloneURLSource. The base repository is returned whenever the
+// head repository is private or no longer exists.
+func (s *service) getCloneURLFromPR(pr *github.PullRequest) string {
+	if s.config.PRCloneURLSource == PRCloneURLSourceHead {
+		if headRepo := pr.GetHead().GetRepo(); headRepo != nil &&
+			headRepo.GetFullName() != "" && !headRepo.GetPrivate() {
+			return s.getCloneURL(headRepo)
+		}
+	}
+	return s.getCloneURL(pr.GetBase().GetRepo())
+}
+
+// getCloneURL returns the URL Brigade should use for cloning the specified
+// repository, according to the service's configured PRCloneURLProtocol. If
+// Brigade cannot be assumed to be able to use the URL, an empty string is
+// returned instead, so that Brigade falls back to the project's own clone URL.
+func (s *service) getCloneURL(repo *github.Repository) string {
+	if s.config.PRCloneURLProtocol == PRCloneURLProtocolSSH {
+		return repo.GetSSHURL()
+	}
+	if repo.GetPrivate() {
+		return ""
+	}
+	return repo.GetCloneURL()
+}
+
 // getTitlesFromPR extracts human-readable titles from a
 // github.PullRequest.
 func getTitlesFromPR(pr *github.PullRequest) (string, string) {
@@ -689,3 +1819,17 @@ func getTitlesFromPR(pr *github.PullRequest) (string, string) {
 	}
 	return shortTitle, longTitle
 }
+
+// getTitlesFromPackage extracts human-readable titles from a package published
+// to GitHub Packages.
+func getTitlesFromPackage(pkg *registryPackage) (string, string) {
+	var shortTitle, longTitle string
+	if pkg.GetName() != "" {
+		shortTitle = pkg.GetName()
+		longTitle = shortTitle
+		if version := pkg.GetPackageVersion().GetVersion(); version != "" {
+			longTitle = fmt.Sprintf("%s %s", shortTitle, version)
+		}
+	}
+	return shortTitle, longTitle
+}
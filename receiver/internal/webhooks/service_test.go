@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"testing"
 
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade-github-gateway/payloads"
 	"github.com/brigadecore/brigade/sdk/v3"
 	sdkTesting "github.com/brigadecore/brigade/sdk/v3/testing"
 	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,6 +26,13 @@ func TestNewService(t *testing.T) {
 	require.True(t, ok)
 	require.NotNil(t, s.eventsClient)
 	require.NotNil(t, s.config)
+	require.NotNil(t, s.requestCheckSuiteFn)
+	require.NotNil(t, s.removeLabelFn)
+	require.NotNil(t, s.getAuthorAssociationFn)
+	require.NotNil(t, s.getPRFromIssueCommentWebhookFn)
+	require.NotNil(t, s.listPRFilesFn)
+	require.NotNil(t, s.listPRsForCommitFn)
+	require.NotNil(t, s.checkRunsClientFactory)
 }
 
 func TestHandle(t *testing.T) {
@@ -35,6 +45,14 @@ func TestHandle(t *testing.T) {
 	testQualifiers := map[string]string{
 		"repo": "brigadecore/brigade-github-gateway",
 	}
+	testOrg := &github.Organization{
+		Login: github.String("brigadecore"),
+	}
+	testOrgQualifiers := map[string]string{
+		"org": "brigadecore",
+	}
+	testPayloadStore, err := payloads.NewFilesystemStore(t.TempDir())
+	require.NoError(t, err)
 
 	testCases := []struct {
 		name         string
@@ -218,6 +236,32 @@ func TestHandle(t *testing.T) {
 			},
 		},
 
+		{
+			name:        "check_run webhook for check run created by this gateway",
+			webhookType: "check_run",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.CheckRunEvent{
+						Action: github.String("completed"),
+						Repo:   testRepo,
+						CheckRun: &github.CheckRun{
+							Name: github.String("italian:pizza"),
+							App: &github.App{
+								ID: github.Int64(42),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Empty(t, events.Items)
+			},
+		},
+
 		{
 			name:        "check_suite webhook",
 			webhookType: "check_suite",
@@ -277,6 +321,186 @@ func TestHandle(t *testing.T) {
 			},
 		},
 
+		{
+			name:        "check_suite webhook; forwarded rerequest; bots filtered",
+			webhookType: "check_suite",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.CheckSuiteEvent{
+						Action: github.String("rerequested"),
+						Repo:   testRepo,
+						Sender: &github.User{
+							Login: github.String("brigade[bot]"),
+							Type:  github.String("Bot"),
+						},
+						CheckSuite: &github.CheckSuite{
+							HeadSHA:    github.String(testSHA),
+							HeadBranch: github.String(testBranch),
+							App: &github.App{
+								ID:   github.Int64(42),
+								Slug: github.String("brigade"),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					SenderFilters: []SenderFilter{
+						{
+							Name: "bots",
+							Bots: true,
+						},
+					},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				// The gateway's own forwarded rerequest must not be ignored
+				require.Len(t, events.Items, 2)
+				require.Equal(t, "check_suite:rerequested", events.Items[0].Type)
+				require.Equal(t, "ci:pipeline_requested", events.Items[1].Type)
+			},
+		},
+
+		{
+			name:        "check_suite webhook; other app's bot; bots filtered",
+			webhookType: "check_suite",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.CheckSuiteEvent{
+						Action: github.String("rerequested"),
+						Repo:   testRepo,
+						Sender: &github.User{
+							Login: github.String("other-ci[bot]"),
+							Type:  github.String("Bot"),
+						},
+						CheckSuite: &github.CheckSuite{
+							HeadSHA:    github.String(testSHA),
+							HeadBranch: github.String(testBranch),
+							App: &github.App{
+								ID:   github.Int64(99),
+								Slug: github.String("other-ci"),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					SenderFilters: []SenderFilter{
+						{
+							Name: "bots",
+							Bots: true,
+						},
+					},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Empty(t, events.Items)
+			},
+		},
+
+		{
+			name:        "check_suite webhook; CI skipped",
+			webhookType: "check_suite",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.CheckSuiteEvent{
+						Action: github.String("requested"),
+						Repo:   testRepo,
+						CheckSuite: &github.CheckSuite{
+							HeadSHA:    github.String(testSHA),
+							HeadBranch: github.String(testBranch),
+							HeadCommit: &github.Commit{
+								Message: github.String("Fix typo [skip ci]"),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					SkipCICheckRunName: "brigade",
+				},
+				checkRunsClientFactory: &ghlib.MockCheckRunsClientFactory{
+					NewCheckRunsClientFn: func(
+						context.Context,
+						int64,
+						int64,
+						[]byte,
+					) (ghlib.CheckRunsClient, error) {
+						return &ghlib.MockCheckRunsClient{
+							CreateCheckRunFn: func(
+								_ context.Context,
+								_ string,
+								_ string,
+								opts github.CreateCheckRunOptions,
+							) (*github.CheckRun, *github.Response, error) {
+								require.Equal(t, "brigade", opts.Name)
+								require.Equal(t, testSHA, opts.HeadSHA)
+								require.Equal(t, "neutral", opts.GetConclusion())
+								return &github.CheckRun{}, nil, nil
+							},
+						}, nil
+					},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "check_suite:requested", event.Type)
+				require.Equal(t, "true", event.Labels["skipped"])
+			},
+		},
+
 		{
 			name:        "create webhook",
 			webhookType: "create",
@@ -321,6 +545,48 @@ func TestHandle(t *testing.T) {
 			},
 		},
 
+		{
+			name:        "create webhook for a tag",
+			webhookType: "create",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.CreateEvent{
+						Repo:    testRepo,
+						Ref:     github.String("v1.0.0"),
+						RefType: github.String("tag"),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				require.Equal(t, "create", events.Items[0].Type)
+				event := events.Items[1]
+				require.Equal(t, "create:tag", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "v1.0.0", event.Labels["tag"])
+				require.Equal(t, "false", event.Labels["prerelease"])
+				require.Equal(t, sdk.GitDetails{Ref: "v1.0.0"}, *event.Git)
+			},
+		},
+
 		{
 			name:        "delete webhook",
 			webhookType: "delete",
@@ -358,6 +624,58 @@ func TestHandle(t *testing.T) {
 			},
 		},
 
+		{
+			name:        "delete webhook for a branch",
+			webhookType: "delete",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.DeleteEvent{
+						Repo:    testRepo,
+						Ref:     github.String("feature"),
+						RefType: github.String("branch"),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				event := events.Items[0]
+				require.Equal(t, "delete", event.Type)
+				require.Nil(t, event.Git)
+				require.Equal(
+					t,
+					map[string]string{
+						"appID":   "42",
+						"ref":     "refs/heads/feature",
+						"refType": "branch",
+					},
+					event.Labels,
+				)
+				event = events.Items[1]
+				require.Equal(t, "delete:branch", event.Type)
+				require.Nil(t, event.Git)
+				require.Equal(t, "refs/heads/feature", event.Labels["ref"])
+				require.Equal(t, "feature", event.Labels["branch"])
+			},
+		},
+
 		{
 			name:        "fork webhook",
 			webhookType: "fork",
@@ -667,19 +985,27 @@ func TestHandle(t *testing.T) {
 		},
 
 		{
-			name:        "issues webhook",
-			webhookType: "issues",
+			name:        "issue_comment webhook with command",
+			webhookType: "issue_comment",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.IssuesEvent{
-						Action: testGenericAction,
+					&github.IssueCommentEvent{
+						Action: github.String("created"),
 						Repo:   testRepo,
+						Issue:  &github.Issue{},
+						Comment: &github.IssueComment{
+							Body:              github.String("/brig triage"),
+							AuthorAssociation: github.String("MEMBER"),
+						},
 					},
 				)
 				require.NoError(t, err)
 				return bytes
 			},
 			service: &service{
+				config: ServiceConfig{
+					CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+				},
 				eventsClient: &sdkTesting.MockEventsClient{
 					CreateFn: func(
 						_ context.Context,
@@ -698,20 +1024,22 @@ func TestHandle(t *testing.T) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
 				event := events.Items[0]
-				require.Equal(t, "issues:foo", event.Type)
-				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Nil(t, event.Git)
+				require.Equal(t, "issue_comment:created", event.Type)
+				require.Equal(t, "triage", event.Labels["command"])
 			},
 		},
-
 		{
-			name:        "label webhook",
-			webhookType: "label",
+			name:        "issue_comment webhook on a PR",
+			webhookType: "issue_comment",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.LabelEvent{
+					&github.IssueCommentEvent{
 						Action: testGenericAction,
 						Repo:   testRepo,
+						Issue: &github.Issue{
+							Number:           github.Int(42),
+							PullRequestLinks: &github.PullRequestLinks{},
+						},
 					},
 				)
 				require.NoError(t, err)
@@ -731,18 +1059,245 @@ func TestHandle(t *testing.T) {
 						}, nil
 					},
 				},
+				getPRFromIssueCommentWebhookFn: func(
+					context.Context,
+					ghlib.App,
+					github.IssueCommentEvent,
+				) (*github.PullRequest, error) {
+					return &github.PullRequest{
+						Number: github.Int(42),
+						Title:  github.String("Life, the universe, and everything"),
+						Head: &github.PullRequestBranch{
+							SHA: github.String(testSHA),
+						},
+					}, nil
+				},
 			},
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
 				event := events.Items[0]
-				require.Equal(t, "label:foo", event.Type)
+				require.Equal(t, "issue_comment:foo", event.Type)
 				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Nil(t, event.Git)
-			},
-		},
-
-		{
+				require.Equal(t, "PR #42", event.ShortTitle)
+				require.Equal(
+					t,
+					"PR #42: Life, the universe, and everything",
+					event.LongTitle,
+				)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/pull/42/head",
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "issue_comment webhook on a PR; check suite forwarded",
+			webhookType: "issue_comment",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.IssueCommentEvent{
+						Action: github.String("created"),
+						Repo:   testRepo,
+						Issue: &github.Issue{
+							Number:           github.Int(42),
+							PullRequestLinks: &github.PullRequestLinks{},
+						},
+						Comment: &github.IssueComment{
+							Body:              github.String("/brig check"),
+							AuthorAssociation: github.String("OWNER"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: func() *service {
+				getPRCalls := 0
+				return &service{
+					config: ServiceConfig{
+						CheckSuiteAllowedAuthorAssociations: []string{"OWNER"},
+					},
+					eventsClient: &sdkTesting.MockEventsClient{
+						CreateFn: func(
+							_ context.Context,
+							event sdk.Event,
+							_ *sdk.EventCreateOptions,
+						) (sdk.EventList, error) {
+							return sdk.EventList{
+								Items: []sdk.Event{
+									event,
+								},
+							}, nil
+						},
+					},
+					requestCheckSuiteFn: func(
+						_ context.Context,
+						_ ghlib.App,
+						_ int64,
+						_ string,
+						_ string,
+						commit string,
+					) error {
+						require.Equal(t, testSHA, commit)
+						return nil
+					},
+					getPRFromIssueCommentWebhookFn: func(
+						context.Context,
+						ghlib.App,
+						github.IssueCommentEvent,
+					) (*github.PullRequest, error) {
+						// Check suite forwarding and the emitted event should share a
+						// single lookup.
+						getPRCalls++
+						require.Equal(t, 1, getPRCalls)
+						return &github.PullRequest{
+							Number: github.Int(42),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
+						}, nil
+					},
+				}
+			}(),
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				require.Equal(t, testSHA, events.Items[0].Git.Commit)
+			},
+		},
+
+		{
+			name:        "issue_comment webhook on a PR; error getting PR",
+			webhookType: "issue_comment",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.IssueCommentEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+						Issue: &github.Issue{
+							Number:           github.Int(42),
+							PullRequestLinks: &github.PullRequestLinks{},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+				getPRFromIssueCommentWebhookFn: func(
+					context.Context,
+					ghlib.App,
+					github.IssueCommentEvent,
+				) (*github.PullRequest, error) {
+					return nil, errors.New("something went wrong")
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "issue_comment:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
+			name:        "issues webhook",
+			webhookType: "issues",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.IssuesEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "issues:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
+			name:        "label webhook",
+			webhookType: "label",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.LabelEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "label:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
 			name:        "member webhook",
 			webhookType: "member",
 			webhookBytes: func() []byte {
@@ -1074,6 +1629,7 @@ func TestHandle(t *testing.T) {
 				event := events.Items[0]
 				require.Equal(t, "pull_request:foo", event.Type)
 				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "false", event.Labels["draft"])
 				require.Equal(
 					t,
 					sdk.GitDetails{
@@ -1086,18 +1642,23 @@ func TestHandle(t *testing.T) {
 		},
 
 		{
-			name:        "pull_request_review webhook",
-			webhookType: "pull_request_review",
+			name:        "pull_request webhook; merged",
+			webhookType: "pull_request",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.PullRequestReviewEvent{
-						Action: testGenericAction,
+					&github.PullRequestEvent{
+						Action: github.String("closed"),
 						Repo:   testRepo,
 						PullRequest: &github.PullRequest{
-							Number: github.Int(42),
+							Number:         github.Int(42),
+							Merged:         github.Bool(true),
+							MergeCommitSHA: github.String("7654321"),
 							Head: &github.PullRequestBranch{
 								SHA: github.String(testSHA),
 							},
+							Base: &github.PullRequestBranch{
+								Ref: github.String(testBranch),
+							},
 						},
 					},
 				)
@@ -1121,9 +1682,9 @@ func TestHandle(t *testing.T) {
 			},
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
-				require.Len(t, events.Items, 1)
+				require.Len(t, events.Items, 2)
 				event := events.Items[0]
-				require.Equal(t, "pull_request_review:foo", event.Type)
+				require.Equal(t, "pull_request:closed", event.Type)
 				require.Equal(t, testQualifiers, event.Qualifiers)
 				require.Equal(
 					t,
@@ -1133,22 +1694,39 @@ func TestHandle(t *testing.T) {
 					},
 					*event.Git,
 				)
+				event = events.Items[1]
+				require.Equal(t, "pull_request:merged", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: "7654321",
+						Ref:    "refs/heads/master",
+					},
+					*event.Git,
+				)
 			},
 		},
 
 		{
-			name:        "pull_request_review_comment webhook",
-			webhookType: "pull_request_review_comment",
+			name:        "pull_request webhook; rich labels enabled",
+			webhookType: "pull_request",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.PullRequestReviewCommentEvent{
-						Action: testGenericAction,
+					&github.PullRequestEvent{
+						Action: github.String("closed"),
 						Repo:   testRepo,
 						PullRequest: &github.PullRequest{
-							Number: github.Int(42),
+							Number:         github.Int(42),
+							Merged:         github.Bool(true),
+							MergeCommitSHA: github.String("7654321"),
 							Head: &github.PullRequestBranch{
+								Ref: github.String("feature"),
 								SHA: github.String(testSHA),
 							},
+							Base: &github.PullRequestBranch{
+								Ref: github.String(testBranch),
+							},
 						},
 					},
 				)
@@ -1156,6 +1734,9 @@ func TestHandle(t *testing.T) {
 				return bytes
 			},
 			service: &service{
+				config: ServiceConfig{
+					RichLabelsEnabled: true,
+				},
 				eventsClient: &sdkTesting.MockEventsClient{
 					CreateFn: func(
 						_ context.Context,
@@ -1172,34 +1753,39 @@ func TestHandle(t *testing.T) {
 			},
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
-				require.Len(t, events.Items, 1)
-				event := events.Items[0]
-				require.Equal(t, "pull_request_review_comment:foo", event.Type)
-				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Equal(
-					t,
-					sdk.GitDetails{
-						Commit: testSHA,
-						Ref:    "refs/pull/42/head",
-					},
-					*event.Git,
-				)
+				require.Len(t, events.Items, 2)
+				for _, event := range events.Items {
+					require.Equal(
+						t,
+						map[string]string{
+							"appID":      "42",
+							"draft":      "false",
+							"action":     "closed",
+							"branch":     "feature",
+							"baseBranch": testBranch,
+							"prNumber":   "42",
+						},
+						event.Labels,
+					)
+				}
 			},
 		},
 
 		{
-			name:        "push webhook",
-			webhookType: "push",
+			name:        "pull_request webhook; closed without merging",
+			webhookType: "pull_request",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.PushEvent{
-						Repo: &github.PushEventRepository{
-							FullName: github.String("brigadecore/brigade-github-gateway"),
-						},
-						HeadCommit: &github.HeadCommit{
-							ID: github.String(testSHA),
+					&github.PullRequestEvent{
+						Action: github.String("closed"),
+						Repo:   testRepo,
+						PullRequest: &github.PullRequest{
+							Number: github.Int(42),
+							Merged: github.Bool(false),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
 						},
-						Ref: github.String(testBranch),
 					},
 				)
 				require.NoError(t, err)
@@ -1223,30 +1809,23 @@ func TestHandle(t *testing.T) {
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
-				event := events.Items[0]
-				require.Equal(t, "push", event.Type)
-				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Equal(
-					t,
-					sdk.GitDetails{
-						Commit: testSHA,
-						Ref:    testBranch,
-					},
-					*event.Git,
-				)
+				require.Equal(t, "pull_request:closed", events.Items[0].Type)
 			},
 		},
 
 		{
-			name:        "release webhook",
-			webhookType: "release",
+			name:        "pull_request_review webhook",
+			webhookType: "pull_request_review",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.ReleaseEvent{
-						Action: github.String("published"),
+					&github.PullRequestReviewEvent{
+						Action: testGenericAction,
 						Repo:   testRepo,
-						Release: &github.RepositoryRelease{
-							TagName: github.String("v0.1.0"),
+						PullRequest: &github.PullRequest{
+							Number: github.Int(42),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
 						},
 					},
 				)
@@ -1270,40 +1849,1792 @@ func TestHandle(t *testing.T) {
 			},
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
-				require.Len(t, events.Items, 2)
+				require.Len(t, events.Items, 1)
 				event := events.Items[0]
-				require.Equal(t, "release:published", event.Type)
+				require.Equal(t, "pull_request_review:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
 				require.Equal(
 					t,
 					sdk.GitDetails{
-						Ref: "v0.1.0",
+						Commit: testSHA,
+						Ref:    "refs/pull/42/head",
 					},
 					*event.Git,
 				)
+			},
+		},
+
+		{
+			name:        "pull_request_review webhook; approved",
+			webhookType: "pull_request_review",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PullRequestReviewEvent{
+						Action: github.String("submitted"),
+						Repo:   testRepo,
+						Review: &github.PullRequestReview{
+							State: github.String("approved"),
+						},
+						PullRequest: &github.PullRequest{
+							Number: github.Int(42),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				require.Equal(
+					t,
+					"pull_request_review:submitted",
+					events.Items[0].Type,
+				)
+				event := events.Items[1]
+				require.Equal(t, "pull_request_review:approved", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/pull/42/head",
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "pull_request_review_comment webhook",
+			webhookType: "pull_request_review_comment",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PullRequestReviewCommentEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+						PullRequest: &github.PullRequest{
+							Number: github.Int(42),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "pull_request_review_comment:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/pull/42/head",
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "push webhook",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PushEvent{
+						Repo: &github.PushEventRepository{
+							FullName: github.String("brigadecore/brigade-github-gateway"),
+						},
+						HeadCommit: &github.HeadCommit{
+							ID: github.String(testSHA),
+						},
+						Ref: github.String(testBranch),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "push", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    testBranch,
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "forced push webhook; all components affected",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PushEvent{
+						Repo: &github.PushEventRepository{
+							FullName: github.String("brigadecore/brigade-github-gateway"),
+						},
+						HeadCommit: &github.HeadCommit{
+							ID: github.String(testSHA),
+						},
+						Ref:    github.String(testBranch),
+						Forced: github.Bool(true),
+						Commits: []*github.HeadCommit{
+							{
+								Modified: []string{"README.md"},
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					ComponentMappings: []ComponentMapping{
+						{
+							Component: "apiserver",
+							Paths:     []string{"v2/apiserver/**"},
+						},
+						{
+							Component: "docs",
+							Paths:     []string{"**/*.md"},
+						},
+					},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "push", event.Type)
+				// Only docs were changed by the commits listed in the payload, but a
+				// forced push may have changed anything.
+				require.Equal(t, "true", event.Labels["component.apiserver"])
+				require.Equal(t, "true", event.Labels["component.docs"])
+			},
+		},
+
+		{
+			name:        "push webhook for a branch",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PushEvent{
+						Repo: &github.PushEventRepository{
+							FullName: github.String("brigadecore/brigade-github-gateway"),
+						},
+						HeadCommit: &github.HeadCommit{
+							ID: github.String(testSHA),
+						},
+						Ref: github.String("refs/heads/main"),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				event := events.Items[0]
+				require.Equal(t, "push", event.Type)
+				require.NotContains(t, event.Labels, "branch")
 				event = events.Items[1]
-				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.Equal(t, "push:branch", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "main", event.Labels["branch"])
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/heads/main",
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "push webhook for a tag",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PushEvent{
+						Repo: &github.PushEventRepository{
+							FullName: github.String("brigadecore/brigade-github-gateway"),
+						},
+						HeadCommit: &github.HeadCommit{
+							ID: github.String(testSHA),
+						},
+						Ref: github.String("refs/tags/v1.2.3-rc.1"),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				require.Equal(t, "push", events.Items[0].Type)
+				event := events.Items[1]
+				require.Equal(t, "push:tag", event.Type)
+				require.Equal(
+					t,
+					map[string]string{
+						"appID":            "42",
+						"tag":              "v1.2.3-rc.1",
+						"semverMajor":      "1",
+						"semverMinor":      "2",
+						"semverPatch":      "3",
+						"semverPrerelease": "rc.1",
+						"prerelease":       "true",
+					},
+					event.Labels,
+				)
+			},
+		},
+
+		{
+			name:        "push webhook for a deleted branch",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.PushEvent{
+						Repo: &github.PushEventRepository{
+							FullName: github.String("brigadecore/brigade-github-gateway"),
+						},
+						Ref:     github.String("refs/heads/feature"),
+						Before:  github.String(testSHA),
+						Deleted: github.Bool(true),
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "push:delete", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+				require.Equal(
+					t,
+					map[string]string{
+						"appID":       "42",
+						"ref":         "refs/heads/feature",
+						"refType":     "branch",
+						"previousSHA": testSHA,
+					},
+					event.Labels,
+				)
+			},
+		},
+
+		{
+			name:        "release webhook",
+			webhookType: "release",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.ReleaseEvent{
+						Action: github.String("published"),
+						Repo:   testRepo,
+						Release: &github.RepositoryRelease{
+							TagName: github.String("v0.1.0"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				event := events.Items[0]
+				require.Equal(t, "release:published", event.Type)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Ref: "v0.1.0",
+					},
+					*event.Git,
+				)
+				event = events.Items[1]
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Ref: "v0.1.0",
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "repository webhook",
+			webhookType: "repository",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.RepositoryEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "repository:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
+			name:        "status webhook",
+			webhookType: "status",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.StatusEvent{
+						Repo: testRepo,
+						Commit: &github.RepositoryCommit{
+							SHA: github.String(testSHA),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "status", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "team_add webhook",
+			webhookType: "team_add",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.TeamAddEvent{
+						Repo: testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "team_add", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
+			name:        "watch webhook",
+			webhookType: "watch",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.WatchEvent{
+						Action: testGenericAction,
+						Repo:   testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "watch:foo", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Nil(t, event.Git)
+			},
+		},
+
+		{
+			name:        "workflow_job webhook",
+			webhookType: "workflow_job",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&workflowJobEvent{
+						Action: github.String("completed"),
+						Repo:   testRepo,
+						WorkflowJob: &workflowJob{
+							Name:         github.String("test"),
+							WorkflowName: github.String("build"),
+							HeadBranch:   github.String(testBranch),
+							HeadSHA:      github.String(testSHA),
+							Conclusion:   github.String("failure"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "workflow_job:completed", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "build", event.Labels["workflow"])
+				require.Equal(t, "failure", event.Labels["conclusion"])
+				require.Equal(t, "test", event.ShortTitle)
+				require.Equal(t, "build: test", event.LongTitle)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    testBranch,
+					},
+					*event.Git,
+				)
+			},
+		},
+
+		{
+			name:        "workflow_run webhook; not a trigger",
+			webhookType: "workflow_run",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&workflowRunEvent{
+						Action: github.String("completed"),
+						Repo:   testRepo,
+						WorkflowRun: &workflowRun{
+							Name:       github.String("lint"),
+							RunNumber:  github.Int(7),
+							HeadBranch: github.String(testBranch),
+							HeadSHA:    github.String(testSHA),
+							Conclusion: github.String("success"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					WorkflowRunTriggers: []string{"build"},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "workflow_run:completed", event.Type)
+				require.Equal(t, "lint", event.Labels["workflow"])
+				require.Equal(t, "success", event.Labels["conclusion"])
+				require.Equal(t, "lint #7", event.LongTitle)
+				require.Nil(t, event.SourceState)
+			},
+		},
+
+		{
+			name:        "workflow_run webhook; trigger failed",
+			webhookType: "workflow_run",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&workflowRunEvent{
+						Action: github.String("completed"),
+						Repo:   testRepo,
+						WorkflowRun: &workflowRun{
+							Name:       github.String("build"),
+							HeadSHA:    github.String(testSHA),
+							Conclusion: github.String("failure"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					WorkflowRunTriggers: []string{"build"},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				require.Equal(t, "workflow_run:completed", events.Items[0].Type)
+				require.Nil(t, events.Items[0].SourceState)
+			},
+		},
+
+		{
+			name:        "workflow_run webhook; trigger succeeded",
+			webhookType: "workflow_run",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&workflowRunEvent{
+						Action: github.String("completed"),
+						Repo:   testRepo,
+						WorkflowRun: &workflowRun{
+							Name:       github.String("build"),
+							HeadBranch: github.String(testBranch),
+							HeadSHA:    github.String(testSHA),
+							Conclusion: github.String("success"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					WorkflowRunTriggers: []string{"build"},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				event := events.Items[0]
+				require.Equal(t, "workflow_run:completed", event.Type)
+				require.NotNil(t, event.SourceState)
+				require.Equal(t, "true", event.SourceState.State["tracking"])
+				require.Equal(t, testSHA, event.SourceState.State["headSHA"])
+				event = events.Items[1]
+				require.Equal(t, "ci:pipeline_requested", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.NotNil(t, event.SourceState)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    testBranch,
+					},
+					*event.Git,
+				)
+			},
+		},
+		{
+			name:        "merge_group webhook",
+			webhookType: "merge_group",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&mergeGroupEvent{
+						Action: github.String("checks_requested"),
+						Repo:   testRepo,
+						MergeGroup: &mergeGroup{
+							HeadSHA: github.String(testSHA),
+							HeadRef: github.String(
+								"refs/heads/gh-readonly-queue/main/pr-42-abcdef",
+							),
+							BaseRef: github.String("refs/heads/main"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				event := events.Items[0]
+				require.Equal(t, "merge_group:checks_requested", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "merge queue: main", event.ShortTitle)
+				require.NotNil(t, event.SourceState)
+				require.Equal(t, "true", event.SourceState.State["tracking"])
+				require.Equal(t, testSHA, event.SourceState.State["headSHA"])
+				event = events.Items[1]
+				require.Equal(t, "ci:pipeline_requested", event.Type)
+				require.NotNil(t, event.SourceState)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/heads/gh-readonly-queue/main/pr-42-abcdef",
+					},
+					*event.Git,
+				)
+			},
+		},
+		{
+			name:        "merge_group webhook; destroyed",
+			webhookType: "merge_group",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&mergeGroupEvent{
+						Action: github.String("destroyed"),
+						Repo:   testRepo,
+						MergeGroup: &mergeGroup{
+							HeadSHA: github.String(testSHA),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				require.Equal(t, "merge_group:destroyed", events.Items[0].Type)
+				require.Nil(t, events.Items[0].SourceState)
+			},
+		},
+		{
+			name:        "code_scanning_alert webhook",
+			webhookType: "code_scanning_alert",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "created",
+					"alert": {
+						"number": 3,
+						"state": "open",
+						"rule": {
+							"id": "js/sql-injection",
+							"severity": "error",
+							"security_severity_level": "high",
+							"description": "Database query built from user-controlled sources"
+						},
+						"tool": {
+							"name": "CodeQL"
+						}
+					},
+					"ref": "refs/heads/master",
+					"commit_oid": "1234567",
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "code_scanning_alert:created", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "high", event.Labels["severity"])
+				require.Equal(t, "js/sql-injection", event.Labels["rule"])
+				require.Equal(t, "open", event.Labels["state"])
+				require.Equal(t, "code scanning alert #3", event.ShortTitle)
+				require.Equal(
+					t,
+					sdk.GitDetails{
+						Commit: testSHA,
+						Ref:    "refs/heads/master",
+					},
+					*event.Git,
+				)
+			},
+		},
+		{
+			name:        "secret_scanning_alert webhook",
+			webhookType: "secret_scanning_alert",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "created",
+					"alert": {
+						"number": 7,
+						"secret_type": "github_personal_access_token",
+						"secret_type_display_name": "GitHub Personal Access Token",
+						"state": "open"
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "secret_scanning_alert:created", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					"github_personal_access_token",
+					event.Labels["rule"],
+				)
+				require.Equal(t, "open", event.Labels["state"])
+				require.NotContains(t, event.Labels, "severity")
+				require.Equal(t, "GitHub Personal Access Token", event.LongTitle)
+			},
+		},
+		{
+			name:        "dependabot_alert webhook",
+			webhookType: "dependabot_alert",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "created",
+					"alert": {
+						"number": 2,
+						"state": "open",
+						"dependency": {
+							"package": {
+								"ecosystem": "npm",
+								"name": "lodash"
+							},
+							"manifest_path": "package-lock.json",
+							"scope": "runtime"
+						},
+						"security_advisory": {
+							"ghsa_id": "GHSA-35jh-r3h4-6jhm",
+							"cve_id": "CVE-2021-23337",
+							"summary": "Command Injection in lodash",
+							"severity": "high"
+						},
+						"security_vulnerability": {
+							"package": {
+								"ecosystem": "npm",
+								"name": "lodash"
+							},
+							"severity": "high",
+							"vulnerable_version_range": "< 4.17.21"
+						}
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "dependabot_alert:created", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "high", event.Labels["severity"])
+				require.Equal(t, "lodash", event.Labels["package"])
+				require.Equal(t, "open", event.Labels["state"])
+				require.Equal(t, "Command Injection in lodash", event.LongTitle)
+			},
+		},
+		{
+			name:        "repository_vulnerability_alert webhook",
+			webhookType: "repository_vulnerability_alert",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "create",
+					"alert": {
+						"id": 91095730,
+						"number": 2,
+						"state": "open",
+						"affected_range": "< 4.17.21",
+						"affected_package_name": "lodash",
+						"severity": "high",
+						"ghsa_id": "GHSA-35jh-r3h4-6jhm",
+						"fixed_in": "4.17.21"
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "repository_vulnerability_alert:create", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "high", event.Labels["severity"])
+				require.Equal(t, "lodash", event.Labels["package"])
+				require.Equal(t, "open", event.Labels["state"])
+				require.Equal(t, "vulnerability alert: lodash", event.ShortTitle)
+			},
+		},
+		{
+			name:        "security_advisory webhook",
+			webhookType: "security_advisory",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "published",
+					"security_advisory": {
+						"ghsa_id": "GHSA-35jh-r3h4-6jhm",
+						"cve_id": "CVE-2021-23337",
+						"summary": "Command Injection in lodash",
+						"severity": "high",
+						"vulnerabilities": [
+							{
+								"package": {
+									"ecosystem": "npm",
+									"name": "lodash"
+								},
+								"severity": "high",
+								"vulnerable_version_range": "< 4.17.21"
+							}
+						]
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "security_advisory:published", event.Type)
+				require.Empty(t, event.Qualifiers)
+				require.Equal(t, "high", event.Labels["severity"])
+				require.Equal(t, "lodash", event.Labels["package"])
+				require.Equal(t, "GHSA-35jh-r3h4-6jhm", event.ShortTitle)
+			},
+		},
+		{
+			name:        "repository_dispatch webhook; not permitted",
+			webhookType: "repository_dispatch",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "deploy-staging",
+					"branch": "master",
+					"client_payload": {
+						"env": "staging",
+						"appID": "spoofed"
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						context.Context,
+						sdk.Event,
+						*sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						require.Fail(t, "create event should not have been called")
+						return sdk.EventList{}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Empty(t, events.Items)
+			},
+		},
+		{
+			name:        "repository_dispatch webhook; permitted",
+			webhookType: "repository_dispatch",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "deploy-staging",
+					"branch": "master",
+					"client_payload": {
+						"env": "staging",
+						"appID": "spoofed",
+						"tag": "v1.0.0"
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				config: ServiceConfig{
+					DispatchPolicies: []DispatchPolicy{
+						{
+							Name:        "deployments",
+							Repos:       []string{"brigadecore"},
+							EventTypes:  []string{"deploy-*"},
+							PayloadKeys: []string{"*"},
+						},
+					},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "dispatch:deploy-staging", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(
+					t,
+					// Labels from the client payload must not masquerade as labels set
+					// by the gateway.
+					map[string]string{
+						"appID":         "42",
+						"payload.env":   "staging",
+						"payload.appID": "spoofed",
+						"payload.tag":   "v1.0.0",
+					},
+					event.Labels,
+				)
+				require.Equal(t, sdk.GitDetails{Ref: testBranch}, *event.Git)
+			},
+		},
+		{
+			name:        "organization webhook",
+			webhookType: "organization",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.OrganizationEvent{
+						Action:       github.String("member_added"),
+						Organization: testOrg,
+						Membership: &github.Membership{
+							User: &github.User{
+								Login: github.String("krancour"),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "organization:member_added", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "krancour", event.Labels["member"])
+			},
+		},
+		{
+			name:        "organization webhook; member invited",
+			webhookType: "organization",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.OrganizationEvent{
+						Action:       github.String("member_invited"),
+						Organization: testOrg,
+						Invitation: &github.Invitation{
+							Login: github.String("krancour"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "organization:member_invited", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "krancour", event.Labels["member"])
+			},
+		},
+		{
+			name:        "team webhook",
+			webhookType: "team",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.TeamEvent{
+						Action: github.String("added_to_repository"),
+						Org:    testOrg,
+						Team: &github.Team{
+							Slug: github.String("maintainers"),
+						},
+						Repo: testRepo,
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "team:added_to_repository", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "maintainers", event.Labels["team"])
+				require.Equal(
+					t,
+					"brigadecore/brigade-github-gateway",
+					event.Labels["repo"],
+				)
+			},
+		},
+		{
+			name:        "membership webhook",
+			webhookType: "membership",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.MembershipEvent{
+						Action: github.String("added"),
+						Scope:  github.String("team"),
+						Org:    testOrg,
+						Team: &github.Team{
+							Slug: github.String("maintainers"),
+						},
+						Member: &github.User{
+							Login: github.String("krancour"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "membership:added", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "maintainers", event.Labels["team"])
+				require.Equal(t, "krancour", event.Labels["member"])
+			},
+		},
+		{
+			name:        "org_block webhook",
+			webhookType: "org_block",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&github.OrgBlockEvent{
+						Action:       github.String("blocked"),
+						Organization: testOrg,
+						BlockedUser: &github.User{
+							Login: github.String("spammer"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "org_block:blocked", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "spammer", event.Labels["blockedUser"])
+			},
+		},
+		{
+			name:        "installation_target webhook",
+			webhookType: "installation_target",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					&installationTargetEvent{
+						Action: github.String("renamed"),
+						Account: &github.User{
+							Login: github.String("brigadecore"),
+						},
+						TargetType: github.String("Organization"),
+						Changes: &installationTargetChanges{
+							Login: &github.Rename{
+								From: github.String("brigade"),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "installation_target:renamed", event.Type)
+				require.Equal(t, testOrgQualifiers, event.Qualifiers)
+				require.Equal(t, "brigade", event.Labels["previousLogin"])
+			},
+		},
+		{
+			name:        "discussion webhook",
+			webhookType: "discussion",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "created",
+					"discussion": {
+						"number": 42,
+						"title": "How do I configure check suite policies?",
+						"body": "I can't figure it out.",
+						"category": {
+							"name": "Q&A",
+							"slug": "q-a",
+							"is_answerable": true
+						},
+						"author_association": "NONE"
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				config: ServiceConfig{
+					CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "discussion:created", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "42", event.Labels["discussionNumber"])
+				require.Equal(t, "q-a", event.Labels["category"])
+				require.NotContains(t, event.Labels, "command")
+				require.Equal(t, "discussion #42", event.ShortTitle)
+				require.Equal(
+					t,
+					"How do I configure check suite policies?",
+					event.LongTitle,
+				)
+			},
+		},
+		{
+			name:        "discussion_comment webhook with command",
+			webhookType: "discussion_comment",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "created",
+					"comment": {
+						"body": "/brig route on-call",
+						"author_association": "MEMBER"
+					},
+					"discussion": {
+						"number": 42,
+						"category": {
+							"slug": "q-a"
+						}
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				config: ServiceConfig{
+					CheckSuiteAllowedAuthorAssociations: []string{"MEMBER"},
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "discussion_comment:created", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "42", event.Labels["discussionNumber"])
+				require.Equal(t, "q-a", event.Labels["category"])
+				require.Equal(t, "route", event.Labels["command"])
+			},
+		},
+		{
+			name:        "package webhook",
+			webhookType: "package",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "published",
+					"package": {
+						"name": "brigade-github-gateway",
+						"ecosystem": "CONTAINER",
+						"package_type": "CONTAINER",
+						"package_version": {
+							"version": "sha256:abcdef",
+							"target_oid": "1234567",
+							"container_metadata": {
+								"tag": {
+									"name": "v1.2.3",
+									"digest": "sha256:abcdef"
+								}
+							}
+						},
+						"registry": {
+							"url": "https://ghcr.io/brigadecore"
+						}
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 1)
+				event := events.Items[0]
+				require.Equal(t, "package:published", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "brigade-github-gateway", event.Labels["package"])
+				require.Equal(t, "sha256:abcdef", event.Labels["version"])
+				require.Equal(t, "CONTAINER", event.Labels["ecosystem"])
+				require.Equal(t, "v1.2.3", event.Labels["packageTag"])
+				require.NotNil(t, event.Git)
+				require.Equal(t, "1234567", event.Git.Commit)
 				require.Equal(
 					t,
-					sdk.GitDetails{
-						Ref: "v0.1.0",
-					},
-					*event.Git,
+					"brigade-github-gateway sha256:abcdef",
+					event.LongTitle,
 				)
 			},
 		},
-
 		{
-			name:        "repository webhook",
-			webhookType: "repository",
+			name:        "registry_package webhook; CD enabled",
+			webhookType: "registry_package",
 			webhookBytes: func() []byte {
-				bytes, err := json.Marshal(
-					&github.RepositoryEvent{
-						Action: testGenericAction,
-						Repo:   testRepo,
+				return []byte(`{
+					"action": "published",
+					"registry_package": {
+						"name": "brigade-github-gateway",
+						"ecosystem": "CONTAINER",
+						"package_version": {
+							"version": "sha256:abcdef",
+							"container_metadata": {
+								"tag": {
+									"name": "v1.2.3"
+								}
+							}
+						}
 					},
-				)
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					}
+				}`)
+			},
+			service: &service{
+				config: ServiceConfig{
+					PackageCDEnabled: true,
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
-				return bytes
+				require.Len(t, events.Items, 2)
+				require.Equal(t, "registry_package:published", events.Items[0].Type)
+				event := events.Items[1]
+				require.Equal(t, "cd:pipeline_requested", event.Type)
+				require.Equal(t, testQualifiers, event.Qualifiers)
+				require.Equal(t, "v1.2.3", event.Labels["packageTag"])
+			},
+		},
+		{
+			name:        "branch_protection_rule webhook; required check removed",
+			webhookType: "branch_protection_rule",
+			webhookBytes: func() []byte {
+				return []byte(`{
+					"action": "edited",
+					"rule": {
+						"name": "main",
+						"required_status_checks": ["my-project:lint"]
+					},
+					"changes": {
+						"required_status_checks": {
+							"from": ["my-project:lint", "my-project:test"]
+						}
+					},
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					},
+					"sender": {
+						"login": "krancour"
+					}
+				}`)
 			},
 			service: &service{
 				eventsClient: &sdkTesting.MockEventsClient{
@@ -1324,26 +3655,49 @@ func TestHandle(t *testing.T) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
 				event := events.Items[0]
-				require.Equal(t, "repository:foo", event.Type)
+				require.Equal(t, "branch_protection_rule:edited", event.Type)
 				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Nil(t, event.Git)
+				require.Equal(t, "main", event.Labels["branchPattern"])
+				require.Equal(t, "krancour", event.Labels["actor"])
+				require.Equal(
+					t,
+					"my-project:test",
+					event.Labels["removedRequiredChecks"],
+				)
+				require.Equal(t, "true", event.Labels["requiredChecksRemoved"])
 			},
 		},
-
 		{
-			name:        "status webhook",
-			webhookType: "status",
+			name:        "repository_ruleset webhook; ruleset deleted",
+			webhookType: "repository_ruleset",
 			webhookBytes: func() []byte {
-				bytes, err := json.Marshal(
-					&github.StatusEvent{
-						Repo: testRepo,
-						Commit: &github.RepositoryCommit{
-							SHA: github.String(testSHA),
+				return []byte(`{
+					"action": "deleted",
+					"repository_ruleset": {
+						"name": "protect-main",
+						"conditions": {
+							"ref_name": {
+								"include": ["~DEFAULT_BRANCH", "refs/heads/release/*"]
+							}
 						},
+						"rules": [
+							{
+								"type": "required_status_checks",
+								"parameters": {
+									"required_status_checks": [
+										{"context": "my-project:test"}
+									]
+								}
+							}
+						]
 					},
-				)
-				require.NoError(t, err)
-				return bytes
+					"repository": {
+						"full_name": "brigadecore/brigade-github-gateway"
+					},
+					"sender": {
+						"login": "krancour"
+					}
+				}`)
 			},
 			service: &service{
 				eventsClient: &sdkTesting.MockEventsClient{
@@ -1364,31 +3718,42 @@ func TestHandle(t *testing.T) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
 				event := events.Items[0]
-				require.Equal(t, "status", event.Type)
+				require.Equal(t, "repository_ruleset:deleted", event.Type)
 				require.Equal(t, testQualifiers, event.Qualifiers)
 				require.Equal(
 					t,
-					sdk.GitDetails{
-						Commit: testSHA,
-					},
-					*event.Git,
+					"~DEFAULT_BRANCH,refs/heads/release/*",
+					event.Labels["branchPattern"],
+				)
+				require.Equal(t, "krancour", event.Labels["actor"])
+				require.Equal(
+					t,
+					"my-project:test",
+					event.Labels["removedRequiredChecks"],
 				)
+				require.Equal(t, "ruleset: protect-main", event.ShortTitle)
 			},
 		},
-
 		{
-			name:        "team_add webhook",
-			webhookType: "team_add",
+			name:        "CloudEvents payload format",
+			webhookType: "release",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.TeamAddEvent{
-						Repo: testRepo,
+					github.ReleaseEvent{
+						Action: github.String("published"),
+						Repo:   testRepo,
+						Release: &github.RepositoryRelease{
+							TagName: github.String("v1.0.0"),
+						},
 					},
 				)
 				require.NoError(t, err)
 				return bytes
 			},
 			service: &service{
+				config: ServiceConfig{
+					PayloadFormat: PayloadFormatCloudEvents,
+				},
 				eventsClient: &sdkTesting.MockEventsClient{
 					CreateFn: func(
 						_ context.Context,
@@ -1405,28 +3770,102 @@ func TestHandle(t *testing.T) {
 			},
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
-				require.Len(t, events.Items, 1)
-				event := events.Items[0]
-				require.Equal(t, "team_add", event.Type)
-				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Nil(t, event.Git)
+				require.Len(t, events.Items, 2)
+				for i, eventType := range []string{
+					"release:published",
+					"cd:pipeline_requested",
+				} {
+					event := events.Items[i]
+					require.Equal(t, eventType, event.Type)
+					require.Equal(t, testQualifiers, event.Qualifiers)
+					envelope := cloudEvent{}
+					err = json.Unmarshal([]byte(event.Payload), &envelope)
+					require.NoError(t, err)
+					require.Equal(t, eventType, envelope.Type)
+					require.Contains(t, string(envelope.Data), `"v1.0.0"`)
+				}
 			},
 		},
-
 		{
-			name:        "watch webhook",
-			webhookType: "watch",
+			name:        "large payload offloaded",
+			webhookType: "release",
 			webhookBytes: func() []byte {
 				bytes, err := json.Marshal(
-					&github.WatchEvent{
-						Action: testGenericAction,
+					github.ReleaseEvent{
+						Action: github.String("published"),
 						Repo:   testRepo,
+						Release: &github.RepositoryRelease{
+							TagName: github.String("v1.0.0"),
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: &service{
+				config: ServiceConfig{
+					PayloadStore:          testPayloadStore,
+					PayloadStoreThreshold: 10,
+				},
+				eventsClient: &sdkTesting.MockEventsClient{
+					CreateFn: func(
+						_ context.Context,
+						event sdk.Event,
+						_ *sdk.EventCreateOptions,
+					) (sdk.EventList, error) {
+						return sdk.EventList{
+							Items: []sdk.Event{
+								event,
+							},
+						}, nil
+					},
+				},
+			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				for _, event := range events.Items {
+					require.NotNil(t, payloads.GetReference(event.Payload))
+					payload, err := payloads.Fetch(
+						context.Background(),
+						testPayloadStore,
+						event.Payload,
+					)
+					require.NoError(t, err)
+					require.Contains(t, payload, `"v1.0.0"`)
+				}
+			},
+		},
+		{
+			name:        "normalized payload",
+			webhookType: "push",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					github.PushEvent{
+						Ref: github.String("refs/heads/main"),
+						Repo: &github.PushEventRepository{
+							FullName: github.String(testRepo.GetFullName()),
+						},
+						HeadCommit: &github.HeadCommit{
+							ID: github.String("1234567"),
+							Author: &github.CommitAuthor{
+								Name: github.String("Kent Rancourt"),
+							},
+						},
+						Commits: []*github.HeadCommit{
+							{
+								Modified: []string{"README.md"},
+							},
+						},
 					},
 				)
 				require.NoError(t, err)
 				return bytes
 			},
 			service: &service{
+				config: ServiceConfig{
+					NormalizedPayloadMode: NormalizedPayloadModePayload,
+				},
 				eventsClient: &sdkTesting.MockEventsClient{
 					CreateFn: func(
 						_ context.Context,
@@ -1441,13 +3880,97 @@ func TestHandle(t *testing.T) {
 					},
 				},
 			},
+			assertions: func(events sdk.EventList, err error) {
+				require.NoError(t, err)
+				require.Len(t, events.Items, 2)
+				normalized := normalizedPayload{}
+				err = json.Unmarshal([]byte(events.Items[0].Payload), &normalized)
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					normalizedPayload{
+						Version:      "v1",
+						Type:         "push",
+						Repo:         testRepo.GetFullName(),
+						Ref:          "refs/heads/main",
+						SHA:          "1234567",
+						Author:       "Kent Rancourt",
+						ChangedFiles: []string{"README.md"},
+					},
+					normalized,
+				)
+			},
+		},
+
+		{
+			name:        "pull_request webhook; components and normalized payload",
+			webhookType: "pull_request",
+			webhookBytes: func() []byte {
+				bytes, err := json.Marshal(
+					github.PullRequestEvent{
+						Action: github.String("synchronize"),
+						Number: github.Int(42),
+						Repo:   testRepo,
+						PullRequest: &github.PullRequest{
+							Number: github.Int(42),
+							Head: &github.PullRequestBranch{
+								SHA: github.String(testSHA),
+							},
+						},
+					},
+				)
+				require.NoError(t, err)
+				return bytes
+			},
+			service: func() *service {
+				listPRFilesCalls := 0
+				return &service{
+					config: ServiceConfig{
+						NormalizedPayloadMode: NormalizedPayloadModePayload,
+						ComponentMappings: []ComponentMapping{
+							{
+								Component: "docs",
+								Paths:     []string{"**/*.md"},
+							},
+						},
+					},
+					listPRFilesFn: func(
+						context.Context,
+						ghlib.App,
+						int64,
+						string,
+						string,
+						int,
+					) ([]string, error) {
+						// Changed files should be listed only once, even though both
+						// components and normalized payloads depend on them.
+						listPRFilesCalls++
+						require.Equal(t, 1, listPRFilesCalls)
+						return []string{"README.md"}, nil
+					},
+					eventsClient: &sdkTesting.MockEventsClient{
+						CreateFn: func(
+							_ context.Context,
+							event sdk.Event,
+							_ *sdk.EventCreateOptions,
+						) (sdk.EventList, error) {
+							return sdk.EventList{
+								Items: []sdk.Event{
+									event,
+								},
+							}, nil
+						},
+					},
+				}
+			}(),
 			assertions: func(events sdk.EventList, err error) {
 				require.NoError(t, err)
 				require.Len(t, events.Items, 1)
-				event := events.Items[0]
-				require.Equal(t, "watch:foo", event.Type)
-				require.Equal(t, testQualifiers, event.Qualifiers)
-				require.Nil(t, event.Git)
+				require.Equal(t, "true", events.Items[0].Labels["component.docs"])
+				normalized := normalizedPayload{}
+				err = json.Unmarshal([]byte(events.Items[0].Payload), &normalized)
+				require.NoError(t, err)
+				require.Equal(t, []string{"README.md"}, normalized.ChangedFiles)
 			},
 		},
 	}
@@ -1457,6 +3980,7 @@ func TestHandle(t *testing.T) {
 				context.Background(),
 				42, // Just a fake app ID
 				testCase.webhookType,
+				"",
 				testCase.webhookBytes(),
 			)
 			for _, event := range events.Items {
@@ -1565,3 +4089,126 @@ func TestGetTitlesFromPR(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCloneURLFromPR(t *testing.T) {
+	testBaseRepo := &github.Repository{
+		FullName: github.String("brigadecore/brigade"),
+		CloneURL: github.String("https://github.com/brigadecore/brigade.git"),
+		SSHURL:   github.String("git@github.com:brigadecore/brigade.git"),
+	}
+	testHeadRepo := &github.Repository{
+		FullName: github.String("krancour/brigade"),
+		CloneURL: github.String("https://github.com/krancour/brigade.git"),
+		SSHURL:   github.String("git@github.com:krancour/brigade.git"),
+	}
+	testCases := []struct {
+		name             string
+		source           PRCloneURLSource
+		protocol         PRCloneURLProtocol
+		pr               *github.PullRequest
+		expectedCloneURL string
+	}{
+		{
+			name:   "base source",
+			source: PRCloneURLSourceBase,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{Repo: testBaseRepo},
+				Head: &github.PullRequestBranch{Repo: testHeadRepo},
+			},
+			expectedCloneURL: "https://github.com/brigadecore/brigade.git",
+		},
+		{
+			name:   "base source; private base repo",
+			source: PRCloneURLSourceBase,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{
+					Repo: &github.Repository{
+						FullName: testBaseRepo.FullName,
+						CloneURL: testBaseRepo.CloneURL,
+						SSHURL:   testBaseRepo.SSHURL,
+						Private:  github.Bool(true),
+					},
+				},
+				Head: &github.PullRequestBranch{Repo: testHeadRepo},
+			},
+			// Brigade should fall back to the project's own clone URL
+			expectedCloneURL: "",
+		},
+		{
+			name:     "base source; private base repo; SSH protocol",
+			source:   PRCloneURLSourceBase,
+			protocol: PRCloneURLProtocolSSH,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{
+					Repo: &github.Repository{
+						FullName: testBaseRepo.FullName,
+						CloneURL: testBaseRepo.CloneURL,
+						SSHURL:   testBaseRepo.SSHURL,
+						Private:  github.Bool(true),
+					},
+				},
+				Head: &github.PullRequestBranch{Repo: testHeadRepo},
+			},
+			expectedCloneURL: "git@github.com:brigadecore/brigade.git",
+		},
+		{
+			name:     "head source; SSH protocol",
+			source:   PRCloneURLSourceHead,
+			protocol: PRCloneURLProtocolSSH,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{Repo: testBaseRepo},
+				Head: &github.PullRequestBranch{Repo: testHeadRepo},
+			},
+			expectedCloneURL: "git@github.com:krancour/brigade.git",
+		},
+		{
+			name:   "head source",
+			source: PRCloneURLSourceHead,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{Repo: testBaseRepo},
+				Head: &github.PullRequestBranch{Repo: testHeadRepo},
+			},
+			expectedCloneURL: "https://github.com/krancour/brigade.git",
+		},
+		{
+			name:   "head source; private head repo",
+			source: PRCloneURLSourceHead,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{Repo: testBaseRepo},
+				Head: &github.PullRequestBranch{
+					Repo: &github.Repository{
+						FullName: testHeadRepo.FullName,
+						CloneURL: testHeadRepo.CloneURL,
+						SSHURL:   testHeadRepo.SSHURL,
+						Private:  github.Bool(true),
+					},
+				},
+			},
+			expectedCloneURL: "https://github.com/brigadecore/brigade.git",
+		},
+		{
+			name:   "head source; head repo deleted",
+			source: PRCloneURLSourceHead,
+			pr: &github.PullRequest{
+				Base: &github.PullRequestBranch{Repo: testBaseRepo},
+				Head: &github.PullRequestBranch{},
+			},
+			expectedCloneURL: "https://github.com/brigadecore/brigade.git",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: ServiceConfig{
+					PRCloneURLSource:   testCase.source,
+					PRCloneURLProtocol: testCase.protocol,
+				},
+			}
+			require.Equal(
+				t,
+				testCase.expectedCloneURL,
+				s.getCloneURLFromPR(testCase.pr),
+			)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+)
+
+// defaultSkipCIMarkers enumerates markers that, when found in a head commit
+// message or PR title, are always recognized as a request to skip CI. These
+// are the same markers GitHub Actions recognizes.
+var defaultSkipCIMarkers = []string{"[skip ci]", "[ci skip]"}
+
+// getSkipCIMarker returns the first marker, either default or configured, that
+// is found in the specified text. Matching is case-insensitive. If no marker
+// is found, an empty string is returned.
+func (s *service) getSkipCIMarker(text string) string {
+	if text == "" {
+		return ""
+	}
+	text = strings.ToLower(text)
+	for _, markers := range [][]string{
+		defaultSkipCIMarkers,
+		s.config.SkipCIMarkers,
+	} {
+		for _, marker := range markers {
+			if marker != "" && strings.Contains(text, strings.ToLower(marker)) {
+				return marker
+			}
+		}
+	}
+	return ""
+}
+
+// getSkipCIText returns the text that should be searched for skip CI markers
+// for the specified webhook. For push and check_suite webhooks, this is the
+// head commit message. For pull_request webhooks, this is the PR title. For all
+// other webhooks, this is an empty string.
+func getSkipCIText(webhook interface{}) string {
+	switch w := webhook.(type) {
+	case *github.CheckSuiteEvent:
+		return w.GetCheckSuite().GetHeadCommit().GetMessage()
+	case *github.PullRequestEvent:
+		return w.GetPullRequest().GetTitle()
+	case *github.PushEvent:
+		return w.GetHeadCommit().GetMessage()
+	}
+	return ""
+}
+
+// createSkippedCheckRun creates a completed check run with a neutral
+// conclusion for the specified commit. This permits branch protection rules
+// that require the check run to be satisfied even though CI was skipped.
+func (s *service) createSkippedCheckRun(
+	ctx context.Context,
+	app ghlib.App,
+	installationID int64,
+	repoOwner string,
+	repoName string,
+	commit string,
+	marker string,
+) error {
+	checkRunsClient, err := s.checkRunsClientFactory.NewCheckRunsClient(
+		ctx,
+		app.AppID,
+		installationID,
+		[]byte(app.APIKey),
+	)
+	if err != nil {
+		return err
+	}
+	title := "CI skipped"
+	summary := fmt.Sprintf(
+		"CI was skipped because the head commit message contains %q.",
+		marker,
+	)
+	_, _, err = checkRunsClient.CreateCheckRun(
+		ctx,
+		repoOwner,
+		repoName,
+		github.CreateCheckRunOptions{
+			Name:       s.config.SkipCICheckRunName,
+			HeadSHA:    commit,
+			Status:     github.String("completed"),
+			Conclusion: github.String("neutral"),
+			Output: &github.CheckRunOutput{
+				Title:   &title,
+				Summary: &summary,
+			},
+		},
+	)
+	return errors.Wrapf(
+		err,
+		"error creating check run %q for installation %d",
+		s.config.SkipCICheckRunName,
+		installationID,
+	)
+}
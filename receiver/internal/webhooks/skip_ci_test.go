@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSkipCIMarker(t *testing.T) {
+	testCases := []struct {
+		name           string
+		markers        []string
+		text           string
+		expectedMarker string
+	}{
+		{
+			name:           "no text",
+			expectedMarker: "",
+		},
+		{
+			name:           "no marker",
+			text:           "Fix typo",
+			expectedMarker: "",
+		},
+		{
+			name:           "default marker",
+			text:           "Fix typo [skip ci]",
+			expectedMarker: "[skip ci]",
+		},
+		{
+			name:           "default marker; different case",
+			text:           "Fix typo [CI Skip]",
+			expectedMarker: "[ci skip]",
+		},
+		{
+			name:           "configured marker",
+			markers:        []string{"[skip brigade]"},
+			text:           "Fix typo [skip brigade]",
+			expectedMarker: "[skip brigade]",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{
+				config: ServiceConfig{
+					SkipCIMarkers: testCase.markers,
+				},
+			}
+			require.Equal(
+				t,
+				testCase.expectedMarker,
+				s.getSkipCIMarker(testCase.text),
+			)
+		})
+	}
+}
+
+func TestGetSkipCIText(t *testing.T) {
+	testCases := []struct {
+		name         string
+		webhook      interface{}
+		expectedText string
+	}{
+		{
+			name: "check_suite webhook",
+			webhook: &github.CheckSuiteEvent{
+				CheckSuite: &github.CheckSuite{
+					HeadCommit: &github.Commit{
+						Message: github.String("foo"),
+					},
+				},
+			},
+			expectedText: "foo",
+		},
+		{
+			name: "pull_request webhook",
+			webhook: &github.PullRequestEvent{
+				PullRequest: &github.PullRequest{
+					Title: github.String("foo"),
+				},
+			},
+			expectedText: "foo",
+		},
+		{
+			name: "push webhook",
+			webhook: &github.PushEvent{
+				HeadCommit: &github.HeadCommit{
+					Message: github.String("foo"),
+				},
+			},
+			expectedText: "foo",
+		},
+		{
+			name:         "other webhook",
+			webhook:      &github.IssuesEvent{},
+			expectedText: "",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expectedText, getSkipCIText(testCase.webhook))
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/brigadecore/brigade/sdk/v3/meta"
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+)
+
+// supersedingScopeKey is the source state key under which the scope within
+// which a tracked event supersedes earlier ones is recorded.
+const supersedingScopeKey = "supersedingScope"
+
+// supersededByKey is the source state key under which the SHA of the commit
+// pertaining to the event that superseded a tracked event is recorded.
+const supersededByKey = "supersededBy"
+
+// getSupersedingScope returns the scope within which tracked events resulting
+// from the specified webhook supersede earlier ones. This is the ID of the
+// repository a commit was pushed to and the name of the branch it was pushed
+// to, separated by a colon. Being derived from the head of a PR rather than the
+// PR itself, the scope is the same for every webhook that conveys a new commit
+// to that branch, including check suites this gateway requested on behalf of a
+// PR from a fork. Only webhooks that convey new commits, such as those for
+// pushes, PR synchronization, newly requested check suites, or check suites
+// requested by this gateway, can supersede earlier events. Re-running checks
+// for an older commit, for instance, must not cancel those for a newer one. If
+// the webhook cannot supersede earlier events, an empty string is returned.
+func (s *service) getSupersedingScope(
+	ctx context.Context,
+	appID int64,
+	webhook interface{},
+) (string, error) {
+	var headRepoID int64
+	var branch string
+	switch w := webhook.(type) {
+	case *github.CheckSuiteEvent:
+		switch w.GetAction() {
+		case "requested":
+			headRepoID = w.GetRepo().GetID()
+			branch = w.GetCheckSuite().GetHeadBranch()
+		case "rerequested":
+			// Check suites this gateway requested are requested for the head of a
+			// PR, which, for a PR from a fork, is a branch in another repository.
+			// Check suite webhooks only list PRs from the same repository, so we
+			// look the PR up by its head commit.
+			if getWebhookSender(w).appID != appID {
+				return "", nil
+			}
+			prs, err := s.listPRsForCommitFn(
+				ctx,
+				s.config.GitHubApps[appID],
+				w.GetInstallation().GetID(),
+				w.GetRepo().GetOwner().GetLogin(),
+				w.GetRepo().GetName(),
+				w.GetCheckSuite().GetHeadSHA(),
+			)
+			if err != nil {
+				return "", err
+			}
+			// If the commit is the head of more than one open PR, we cannot tell
+			// which branch it was pushed to.
+			if len(prs) != 1 {
+				return "", nil
+			}
+			headRepoID = prs[0].GetHead().GetRepo().GetID()
+			branch = prs[0].GetHead().GetRef()
+		default:
+			return "", nil
+		}
+	case *github.PullRequestEvent:
+		if w.GetAction() != "opened" && w.GetAction() != "synchronize" {
+			return "", nil
+		}
+		headRepoID = w.GetPullRequest().GetHead().GetRepo().GetID()
+		branch = w.GetPullRequest().GetHead().GetRef()
+	case *github.PushEvent:
+		headRepoID = w.GetRepo().GetID()
+		branch = getRichLabels(webhook, "")["branch"]
+	default:
+		return "", nil
+	}
+	if headRepoID == 0 || branch == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("%d:%s", headRepoID, branch), nil
+}
+
+// listPRsForCommit returns all open PRs whose head is the specified commit.
+func (s *service) listPRsForCommit(
+	ctx context.Context,
+	app ghlib.App,
+	installationID int64,
+	repoOwner string,
+	repoName string,
+	commit string,
+) ([]*github.PullRequest, error) {
+	ghClient, err := ghlib.NewClient(
+		ctx,
+		app.AppID,
+		installationID,
+		[]byte(app.APIKey),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error creating new client for installation %d",
+			installationID,
+		)
+	}
+	prs, _, err := ghClient.PullRequests.ListPullRequestsWithCommit(
+		ctx,
+		repoOwner,
+		repoName,
+		commit,
+		nil,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error listing pull requests for %s/%s commit %s",
+			repoOwner,
+			repoName,
+			commit,
+		)
+	}
+	headPRs := []*github.PullRequest{}
+	for _, pr := range prs {
+		// Commits may belong to PRs without being their head.
+		if pr.GetState() == "open" && pr.GetHead().GetSHA() == commit {
+			headPRs = append(headPRs, pr)
+		}
+	}
+	return headPRs, nil
+}
+
+// applySupersedingScope records, in the source state of each of the specified
+// tracked events, the scope within which it supersedes earlier ones. Events
+// derived from one another may share a single source state, so each event is
+// given a new one.
+func (s *service) applySupersedingScope(
+	ctx context.Context,
+	appID int64,
+	webhook interface{},
+	events []sdk.Event,
+) error {
+	scope, err := s.getSupersedingScope(ctx, appID, webhook)
+	if err != nil || scope == "" {
+		return err
+	}
+	for i, event := range events {
+		if event.SourceState == nil ||
+			event.SourceState.State["tracking"] != "true" {
+			continue
+		}
+		events[i].SourceState = &sdk.SourceState{
+			State: copyLabels(
+				event.SourceState.State,
+				map[string]string{
+					supersedingScopeKey: scope,
+				},
+			),
+		}
+	}
+	return nil
+}
+
+// cancelSupersededEvents cancels any earlier tracked events that are superseded
+// by the specified ones. An earlier event is superseded by a later one if both
+// pertain to the same repository and have the same superseding scope, but
+// pertain to different commits. Before it is canceled, the SHA of the commit
+// pertaining to the later event is recorded in the earlier event's source
+// state so that the monitor can report on its check runs accordingly. Errors
+// are logged, but otherwise ignored, since failing to cancel superseded events
+// must not prevent new ones from being handled.
+func (s *service) cancelSupersededEvents(
+	ctx context.Context,
+	events []sdk.Event,
+) {
+	// Events derived from a single webhook often share a repository, scope, and
+	// commit, so we only need to look for events they supersede once.
+	handled := map[string]struct{}{}
+	for _, event := range events {
+		if event.SourceState == nil ||
+			event.SourceState.State[supersedingScopeKey] == "" {
+			continue
+		}
+		state := event.SourceState.State
+		key := strings.Join(
+			[]string{
+				state["owner"],
+				state["repo"],
+				state[supersedingScopeKey],
+				state["headSHA"],
+			},
+			"/",
+		)
+		if _, ok := handled[key]; ok {
+			continue
+		}
+		handled[key] = struct{}{}
+		if err := s.cancelEventsSupersededBy(ctx, event); err != nil {
+			log.Printf("error canceling superseded events: %s", err)
+		}
+	}
+}
+
+// cancelEventsSupersededBy cancels all earlier tracked events that are
+// superseded by the specified one.
+func (s *service) cancelEventsSupersededBy(
+	ctx context.Context,
+	event sdk.Event,
+) error {
+	state := event.SourceState.State
+	selector := &sdk.EventsSelector{
+		Source: event.Source,
+		SourceState: map[string]string{
+			"tracking":          "true",
+			"owner":             state["owner"],
+			"repo":              state["repo"],
+			supersedingScopeKey: state[supersedingScopeKey],
+		},
+		// Events in any other phase have already finished.
+		WorkerPhases: []sdk.WorkerPhase{
+			sdk.WorkerPhasePending,
+			sdk.WorkerPhaseStarting,
+			sdk.WorkerPhaseRunning,
+		},
+	}
+	// Canceling an event changes its phase, which would shift the pages of a
+	// list we are in the middle of, so we find all superseded events before
+	// canceling any of them.
+	supersededEvents := []sdk.Event{}
+	listOpts := &meta.ListOptions{Limit: 100}
+	for {
+		events, err := s.eventsClient.List(ctx, selector, listOpts)
+		if err != nil {
+			return errors.Wrap(err, "error listing events")
+		}
+		for _, supersededEvent := range events.Items {
+			// Events pertaining to the same commit, including those we just
+			// emitted, are not superseded.
+			if supersededEvent.SourceState != nil &&
+				supersededEvent.SourceState.State["headSHA"] != state["headSHA"] {
+				supersededEvents = append(supersededEvents, supersededEvent)
+			}
+		}
+		if events.RemainingItemCount == 0 {
+			break
+		}
+		listOpts.Continue = events.Continue
+	}
+	for _, supersededEvent := range supersededEvents {
+		if err := s.cancelSupersededEvent(
+			ctx,
+			supersededEvent,
+			state["headSHA"],
+		); err != nil {
+			// Log the error and move on. We should still try to cancel any other
+			// superseded events.
+			log.Println(err)
+		}
+	}
+	return nil
+}
+
+// cancelSupersededEvent records, in the source state of the specified event,
+// the SHA of the commit pertaining to the event that superseded it and then
+// cancels it.
+func (s *service) cancelSupersededEvent(
+	ctx context.Context,
+	event sdk.Event,
+	supersededBy string,
+) error {
+	if err := s.eventsClient.UpdateSourceState(
+		ctx,
+		event.ID,
+		sdk.SourceState{
+			State: copyLabels(
+				event.SourceState.State,
+				map[string]string{
+					supersededByKey: supersededBy,
+				},
+			),
+		},
+		nil,
+	); err != nil {
+		return errors.Wrapf(
+			err,
+			"error updating source state for superseded event %q",
+			event.ID,
+		)
+	}
+	return errors.Wrapf(
+		s.eventsClient.Cancel(ctx, event.ID, nil),
+		"error canceling superseded event %q",
+		event.ID,
+	)
+}
@@ -0,0 +1,417 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	ghlib "github.com/brigadecore/brigade-github-gateway/internal/github"
+	"github.com/brigadecore/brigade/sdk/v3"
+	"github.com/brigadecore/brigade/sdk/v3/meta"
+	sdkTesting "github.com/brigadecore/brigade/sdk/v3/testing"
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSupersedingScope(t *testing.T) {
+	const testAppID = 42
+	testRepo := &github.Repository{
+		ID:   github.Int64(1),
+		Name: github.String("brigade-github-gateway"),
+		Owner: &github.User{
+			Login: github.String("brigadecore"),
+		},
+	}
+	testPR := &github.PullRequest{
+		Number: github.Int(42),
+		State:  github.String("open"),
+		Head: &github.PullRequestBranch{
+			Ref: github.String("feature"),
+			SHA: github.String("1234567"),
+			Repo: &github.Repository{
+				ID: github.Int64(2),
+			},
+		},
+	}
+	testForwardedCheckSuite := func() *github.CheckSuiteEvent {
+		return &github.CheckSuiteEvent{
+			Action: github.String("rerequested"),
+			CheckSuite: &github.CheckSuite{
+				HeadBranch: github.String("feature"),
+				HeadSHA:    github.String("1234567"),
+				App: &github.App{
+					ID:   github.Int64(testAppID),
+					Slug: github.String("brigade"),
+				},
+			},
+			Repo: testRepo,
+			Sender: &github.User{
+				Login: github.String("brigade[bot]"),
+				Type:  github.String("Bot"),
+			},
+		}
+	}
+	testCases := []struct {
+		name       string
+		webhook    interface{}
+		service    *service
+		assertions func(scope string, err error)
+	}{
+		{
+			name: "check_suite:requested webhook",
+			webhook: &github.CheckSuiteEvent{
+				Action: github.String("requested"),
+				CheckSuite: &github.CheckSuite{
+					HeadBranch:   github.String("feature"),
+					PullRequests: []*github.PullRequest{testPR},
+				},
+				Repo: testRepo,
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "1:feature", scope)
+			},
+		},
+		{
+			name: "check_suite:rerequested webhook from a user",
+			webhook: &github.CheckSuiteEvent{
+				Action: github.String("rerequested"),
+				CheckSuite: &github.CheckSuite{
+					HeadBranch: github.String("main"),
+				},
+				Repo: testRepo,
+				Sender: &github.User{
+					Login: github.String("krancour"),
+					Type:  github.String("User"),
+				},
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, scope)
+			},
+		},
+		{
+			name:    "forwarded check_suite:rerequested webhook; error listing PRs",
+			webhook: testForwardedCheckSuite(),
+			service: &service{
+				listPRsForCommitFn: func(
+					context.Context,
+					ghlib.App,
+					int64,
+					string,
+					string,
+					string,
+				) ([]*github.PullRequest, error) {
+					return nil, errors.New("something went wrong")
+				},
+			},
+			assertions: func(_ string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "something went wrong")
+			},
+		},
+		{
+			name:    "forwarded check_suite:rerequested webhook; ambiguous PR",
+			webhook: testForwardedCheckSuite(),
+			service: &service{
+				listPRsForCommitFn: func(
+					context.Context,
+					ghlib.App,
+					int64,
+					string,
+					string,
+					string,
+				) ([]*github.PullRequest, error) {
+					return []*github.PullRequest{testPR, testPR}, nil
+				},
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, scope)
+			},
+		},
+		{
+			name:    "forwarded check_suite:rerequested webhook",
+			webhook: testForwardedCheckSuite(),
+			service: &service{
+				listPRsForCommitFn: func(
+					_ context.Context,
+					_ ghlib.App,
+					_ int64,
+					repoOwner string,
+					repoName string,
+					commit string,
+				) ([]*github.PullRequest, error) {
+					require.Equal(t, "brigadecore", repoOwner)
+					require.Equal(t, "brigade-github-gateway", repoName)
+					require.Equal(t, "1234567", commit)
+					return []*github.PullRequest{testPR}, nil
+				},
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				// The scope should be derived from the PR's head, which is in a fork
+				require.Equal(t, "2:feature", scope)
+			},
+		},
+		{
+			name: "pull_request:synchronize webhook",
+			webhook: &github.PullRequestEvent{
+				Action:      github.String("synchronize"),
+				PullRequest: testPR,
+				Repo:        testRepo,
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "2:feature", scope)
+			},
+		},
+		{
+			name: "pull_request:closed webhook",
+			webhook: &github.PullRequestEvent{
+				Action:      github.String("closed"),
+				PullRequest: testPR,
+				Repo:        testRepo,
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, scope)
+			},
+		},
+		{
+			name: "push webhook for a branch",
+			webhook: &github.PushEvent{
+				Ref: github.String("refs/heads/main"),
+				Repo: &github.PushEventRepository{
+					ID: github.Int64(1),
+				},
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "1:main", scope)
+			},
+		},
+		{
+			name: "push webhook for a tag",
+			webhook: &github.PushEvent{
+				Ref: github.String("refs/tags/v1.0.0"),
+				Repo: &github.PushEventRepository{
+					ID: github.Int64(1),
+				},
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, scope)
+			},
+		},
+		{
+			name: "check_run webhook",
+			webhook: &github.CheckRunEvent{
+				Action: github.String("rerequested"),
+				CheckRun: &github.CheckRun{
+					CheckSuite: &github.CheckSuite{
+						HeadBranch: github.String("main"),
+					},
+				},
+				Repo: testRepo,
+			},
+			assertions: func(scope string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, scope)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := testCase.service
+			if s == nil {
+				s = &service{}
+			}
+			testCase.assertions(
+				s.getSupersedingScope(
+					context.Background(),
+					testAppID,
+					testCase.webhook,
+				),
+			)
+		})
+	}
+}
+
+func TestApplySupersedingScope(t *testing.T) {
+	testSourceState := &sdk.SourceState{
+		State: map[string]string{
+			"tracking": "true",
+			"headSHA":  "1234567",
+		},
+	}
+	events := []sdk.Event{
+		{
+			Type:        "check_suite:requested",
+			SourceState: testSourceState,
+		},
+		{
+			Type:        "ci:pipeline_requested",
+			SourceState: testSourceState,
+		},
+		{
+			Type: "push",
+		},
+	}
+	err := (&service{}).applySupersedingScope(
+		context.Background(),
+		42,
+		&github.CheckSuiteEvent{
+			Action: github.String("requested"),
+			CheckSuite: &github.CheckSuite{
+				HeadBranch: github.String("main"),
+			},
+			Repo: &github.Repository{
+				ID: github.Int64(1),
+			},
+		},
+		events,
+	)
+	require.NoError(t, err)
+	for _, event := range events[:2] {
+		require.Equal(
+			t,
+			map[string]string{
+				"tracking":         "true",
+				"headSHA":          "1234567",
+				"supersedingScope": "1:main",
+			},
+			event.SourceState.State,
+		)
+	}
+	require.Nil(t, events[2].SourceState)
+	// The original source state should not have been modified
+	require.NotContains(t, testSourceState.State, supersedingScopeKey)
+}
+
+func TestCancelSupersededEvents(t *testing.T) {
+	const testNewSHA = "89abcde"
+	testState := func(headSHA string) map[string]string {
+		return map[string]string{
+			"tracking":         "true",
+			"owner":            "brigadecore",
+			"repo":             "brigade-github-gateway",
+			"headSHA":          headSHA,
+			"supersedingScope": "1:feature",
+		}
+	}
+	listCalls := 0
+	updatedStates := map[string]map[string]string{}
+	canceledEventIDs := []string{}
+	s := &service{
+		eventsClient: &sdkTesting.MockEventsClient{
+			ListFn: func(
+				_ context.Context,
+				selector *sdk.EventsSelector,
+				opts *meta.ListOptions,
+			) (sdk.EventList, error) {
+				listCalls++
+				require.Equal(t, "brigade.sh/github", selector.Source)
+				require.Equal(
+					t,
+					map[string]string{
+						"tracking":         "true",
+						"owner":            "brigadecore",
+						"repo":             "brigade-github-gateway",
+						"supersedingScope": "1:feature",
+					},
+					selector.SourceState,
+				)
+				if opts.Continue == "" {
+					return sdk.EventList{
+						ListMeta: meta.ListMeta{
+							Continue:           "old",
+							RemainingItemCount: 1,
+						},
+						Items: []sdk.Event{
+							{
+								ObjectMeta: meta.ObjectMeta{
+									ID: "new",
+								},
+								SourceState: &sdk.SourceState{
+									State: testState(testNewSHA),
+								},
+							},
+							{
+								ObjectMeta: meta.ObjectMeta{
+									ID: "old",
+								},
+								SourceState: &sdk.SourceState{
+									State: testState("1234567"),
+								},
+							},
+						},
+					}, nil
+				}
+				require.Equal(t, "old", opts.Continue)
+				return sdk.EventList{
+					Items: []sdk.Event{
+						{
+							ObjectMeta: meta.ObjectMeta{
+								ID: "older",
+							},
+							SourceState: &sdk.SourceState{
+								State: testState("0123456"),
+							},
+						},
+					},
+				}, nil
+			},
+			UpdateSourceStateFn: func(
+				_ context.Context,
+				id string,
+				sourceState sdk.SourceState,
+				_ *sdk.EventSourceStateUpdateOptions,
+			) error {
+				// All superseded events should have been found before any of them were
+				// canceled.
+				require.Equal(t, 2, listCalls)
+				updatedStates[id] = sourceState.State
+				return nil
+			},
+			CancelFn: func(
+				_ context.Context,
+				id string,
+				_ *sdk.EventCancelOptions,
+			) error {
+				canceledEventIDs = append(canceledEventIDs, id)
+				if id == "old" {
+					return errors.New("something went wrong")
+				}
+				return nil
+			},
+		},
+	}
+	newEvent := sdk.Event{
+		Source: "brigade.sh/github",
+		SourceState: &sdk.SourceState{
+			State: testState(testNewSHA),
+		},
+	}
+	s.cancelSupersededEvents(
+		context.Background(),
+		[]sdk.Event{
+			newEvent,
+			newEvent,
+			{
+				Source: "brigade.sh/github",
+			},
+		},
+	)
+	// Both new events share a repository, scope, and commit, so we should only
+	// have looked for events they supersede once, which took two pages.
+	require.Equal(t, 2, listCalls)
+	// Failing to cancel one superseded event should not have prevented us from
+	// canceling the other.
+	require.Equal(t, []string{"old", "older"}, canceledEventIDs)
+	require.Len(t, updatedStates, 2)
+	for _, id := range []string{"old", "older"} {
+		require.Equal(t, testNewSHA, updatedStates[id][supersededByKey])
+		require.Equal(t, "1:feature", updatedStates[id][supersedingScopeKey])
+	}
+}
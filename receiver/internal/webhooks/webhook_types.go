@@ -0,0 +1,379 @@
+package webhooks
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/v33/github"
+)
+
+// parseWebHook parses the specified payload into a struct of the type that
+// corresponds to the specified webhook type. For a handful of webhook types
+// that the version of go-github we depend on does not support, or does not
+// support fully, this returns a pointer to one of the types defined in this
+// package. For all other webhook types, this defers to github.ParseWebHook.
+func parseWebHook(webhookType string, payload []byte) (interface{}, error) {
+	var webhook interface{}
+	switch webhookType {
+	case "branch_protection_rule":
+		webhook = &branchProtectionRuleEvent{}
+	case "code_scanning_alert":
+		webhook = &codeScanningAlertEvent{}
+	case "dependabot_alert":
+		webhook = &dependabotAlertEvent{}
+	case "discussion":
+		webhook = &discussionEvent{}
+	case "discussion_comment":
+		webhook = &discussionCommentEvent{}
+	case "installation_target":
+		webhook = &installationTargetEvent{}
+	case "merge_group":
+		webhook = &mergeGroupEvent{}
+	case "package":
+		webhook = &packageEvent{}
+	case "registry_package":
+		webhook = &registryPackageEvent{}
+	case "repository_ruleset":
+		webhook = &repositoryRulesetEvent{}
+	case "repository_vulnerability_alert":
+		webhook = &repositoryVulnerabilityAlertEvent{}
+	case "secret_scanning_alert":
+		webhook = &secretScanningAlertEvent{}
+	case "security_advisory":
+		webhook = &securityAdvisoryEvent{}
+	case "workflow_job":
+		webhook = &workflowJobEvent{}
+	case "workflow_run":
+		webhook = &workflowRunEvent{}
+	default:
+		return github.ParseWebHook(webhookType, payload)
+	}
+	return webhook, json.Unmarshal(payload, webhook)
+}
+
+// installationTargetEvent represents an installation_target webhook, which the
+// version of go-github we depend on does not support. The account property
+// describes the organization or user that the GitHub App is installed on.
+type installationTargetEvent struct {
+	Action       *string                    `json:"action,omitempty"`
+	Account      *github.User               `json:"account,omitempty"`
+	TargetType   *string                    `json:"target_type,omitempty"`
+	Changes      *installationTargetChanges `json:"changes,omitempty"`
+	Sender       *github.User               `json:"sender,omitempty"`
+	Installation *github.Installation       `json:"installation,omitempty"`
+}
+
+// installationTargetChanges represents changes to the organization or user
+// that a GitHub App is installed on.
+type installationTargetChanges struct {
+	Login *github.Rename `json:"login,omitempty"`
+}
+
+// mergeGroupEvent represents a merge_group webhook, which the version of
+// go-github we depend on does not support.
+type mergeGroupEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	MergeGroup   *mergeGroup          `json:"merge_group,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// mergeGroup represents a group of PRs in a merge queue, whose combined
+// changes are tested on a temporary branch before being merged.
+type mergeGroup struct {
+	HeadSHA *string `json:"head_sha,omitempty"`
+	HeadRef *string `json:"head_ref,omitempty"`
+	BaseSHA *string `json:"base_sha,omitempty"`
+	BaseRef *string `json:"base_ref,omitempty"`
+}
+
+// workflowRunEvent represents a workflow_run webhook. It is used in lieu of
+// github.WorkflowRunEvent, which lacks the workflow_run property.
+type workflowRunEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	WorkflowRun  *workflowRun         `json:"workflow_run,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// workflowRun represents a GitHub Actions workflow run. It is used in lieu of
+// github.WorkflowRun, which lacks the name property.
+type workflowRun struct {
+	ID         *int64  `json:"id,omitempty"`
+	Name       *string `json:"name,omitempty"`
+	HeadBranch *string `json:"head_branch,omitempty"`
+	HeadSHA    *string `json:"head_sha,omitempty"`
+	RunNumber  *int    `json:"run_number,omitempty"`
+	Event      *string `json:"event,omitempty"`
+	Status     *string `json:"status,omitempty"`
+	Conclusion *string `json:"conclusion,omitempty"`
+}
+
+// workflowJobEvent represents a workflow_job webhook, which the version of
+// go-github we depend on does not support.
+type workflowJobEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	WorkflowJob  *workflowJob         `json:"workflow_job,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// workflowJob represents a job within a GitHub Actions workflow run.
+type workflowJob struct {
+	ID           *int64  `json:"id,omitempty"`
+	RunID        *int64  `json:"run_id,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	WorkflowName *string `json:"workflow_name,omitempty"`
+	HeadBranch   *string `json:"head_branch,omitempty"`
+	HeadSHA      *string `json:"head_sha,omitempty"`
+	Status       *string `json:"status,omitempty"`
+	Conclusion   *string `json:"conclusion,omitempty"`
+}
+
+// The getters below, like those of the webhook types defined in other files in
+// this package, follow the conventions of go-github's generated accessors. They
+// are safe to call on nil receivers and nil fields. Each type has getters only
+// for the fields that Handle, getRichLabels, or the helpers they call make use
+// of, plus GetAction, GetSender, and GetInstallation wherever applicable, since
+// those satisfy the actionGetter, senderGetter, and installationGetter
+// interfaces.
+
+func (i *installationTargetEvent) GetAction() string {
+	if i == nil || i.Action == nil {
+		return ""
+	}
+	return *i.Action
+}
+
+func (i *installationTargetEvent) GetAccount() *github.User {
+	if i == nil {
+		return nil
+	}
+	return i.Account
+}
+
+func (i *installationTargetEvent) GetChanges() *installationTargetChanges {
+	if i == nil {
+		return nil
+	}
+	return i.Changes
+}
+
+func (i *installationTargetEvent) GetSender() *github.User {
+	if i == nil {
+		return nil
+	}
+	return i.Sender
+}
+
+func (i *installationTargetEvent) GetInstallation() *github.Installation {
+	if i == nil {
+		return nil
+	}
+	return i.Installation
+}
+
+func (i *installationTargetChanges) GetLogin() *github.Rename {
+	if i == nil {
+		return nil
+	}
+	return i.Login
+}
+
+func (m *mergeGroupEvent) GetAction() string {
+	if m == nil || m.Action == nil {
+		return ""
+	}
+	return *m.Action
+}
+
+func (m *mergeGroupEvent) GetMergeGroup() *mergeGroup {
+	if m == nil {
+		return nil
+	}
+	return m.MergeGroup
+}
+
+func (m *mergeGroupEvent) GetRepo() *github.Repository {
+	if m == nil {
+		return nil
+	}
+	return m.Repo
+}
+
+func (m *mergeGroupEvent) GetSender() *github.User {
+	if m == nil {
+		return nil
+	}
+	return m.Sender
+}
+
+func (m *mergeGroupEvent) GetInstallation() *github.Installation {
+	if m == nil {
+		return nil
+	}
+	return m.Installation
+}
+
+func (m *mergeGroup) GetHeadSHA() string {
+	if m == nil || m.HeadSHA == nil {
+		return ""
+	}
+	return *m.HeadSHA
+}
+
+func (m *mergeGroup) GetHeadRef() string {
+	if m == nil || m.HeadRef == nil {
+		return ""
+	}
+	return *m.HeadRef
+}
+
+func (m *mergeGroup) GetBaseRef() string {
+	if m == nil || m.BaseRef == nil {
+		return ""
+	}
+	return *m.BaseRef
+}
+
+func (w *workflowRunEvent) GetAction() string {
+	if w == nil || w.Action == nil {
+		return ""
+	}
+	return *w.Action
+}
+
+func (w *workflowRunEvent) GetWorkflowRun() *workflowRun {
+	if w == nil {
+		return nil
+	}
+	return w.WorkflowRun
+}
+
+func (w *workflowRunEvent) GetRepo() *github.Repository {
+	if w == nil {
+		return nil
+	}
+	return w.Repo
+}
+
+func (w *workflowRunEvent) GetSender() *github.User {
+	if w == nil {
+		return nil
+	}
+	return w.Sender
+}
+
+func (w *workflowRunEvent) GetInstallation() *github.Installation {
+	if w == nil {
+		return nil
+	}
+	return w.Installation
+}
+
+func (w *workflowRun) GetName() string {
+	if w == nil || w.Name == nil {
+		return ""
+	}
+	return *w.Name
+}
+
+func (w *workflowRun) GetHeadBranch() string {
+	if w == nil || w.HeadBranch == nil {
+		return ""
+	}
+	return *w.HeadBranch
+}
+
+func (w *workflowRun) GetHeadSHA() string {
+	if w == nil || w.HeadSHA == nil {
+		return ""
+	}
+	return *w.HeadSHA
+}
+
+func (w *workflowRun) GetRunNumber() int {
+	if w == nil || w.RunNumber == nil {
+		return 0
+	}
+	return *w.RunNumber
+}
+
+func (w *workflowRun) GetConclusion() string {
+	if w == nil || w.Conclusion == nil {
+		return ""
+	}
+	return *w.Conclusion
+}
+
+func (w *workflowJobEvent) GetAction() string {
+	if w == nil || w.Action == nil {
+		return ""
+	}
+	return *w.Action
+}
+
+func (w *workflowJobEvent) GetWorkflowJob() *workflowJob {
+	if w == nil {
+		return nil
+	}
+	return w.WorkflowJob
+}
+
+func (w *workflowJobEvent) GetRepo() *github.Repository {
+	if w == nil {
+		return nil
+	}
+	return w.Repo
+}
+
+func (w *workflowJobEvent) GetSender() *github.User {
+	if w == nil {
+		return nil
+	}
+	return w.Sender
+}
+
+func (w *workflowJobEvent) GetInstallation() *github.Installation {
+	if w == nil {
+		return nil
+	}
+	return w.Installation
+}
+
+func (w *workflowJob) GetName() string {
+	if w == nil || w.Name == nil {
+		return ""
+	}
+	return *w.Name
+}
+
+func (w *workflowJob) GetWorkflowName() string {
+	if w == nil || w.WorkflowName == nil {
+		return ""
+	}
+	return *w.WorkflowName
+}
+
+func (w *workflowJob) GetHeadBranch() string {
+	if w == nil || w.HeadBranch == nil {
+		return ""
+	}
+	return *w.HeadBranch
+}
+
+func (w *workflowJob) GetHeadSHA() string {
+	if w == nil || w.HeadSHA == nil {
+		return ""
+	}
+	return *w.HeadSHA
+}
+
+func (w *workflowJob) GetConclusion() string {
+	if w == nil || w.Conclusion == nil {
+		return ""
+	}
+	return *w.Conclusion
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebHook(t *testing.T) {
+	testCases := []struct {
+		name        string
+		webhookType string
+		payload     string
+		assertions  func(interface{}, error)
+	}{
+		{
+			name:        "unknown webhook type",
+			webhookType: "bogus",
+			payload:     "{}",
+			assertions: func(_ interface{}, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:        "webhook type supported by go-github",
+			webhookType: "push",
+			payload:     `{"ref":"refs/heads/main"}`,
+			assertions: func(webhook interface{}, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &github.PushEvent{}, webhook)
+			},
+		},
+		{
+			name:        "installation_target webhook",
+			webhookType: "installation_target",
+			payload:     `{"action":"renamed","account":{"login":"brigadecore"}}`,
+			assertions: func(webhook interface{}, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &installationTargetEvent{}, webhook)
+				// nolint: forcetypeassert
+				w := webhook.(*installationTargetEvent)
+				require.Equal(t, "renamed", w.GetAction())
+				require.Equal(t, "brigadecore", w.GetAccount().GetLogin())
+			},
+		},
+		{
+			name:        "merge_group webhook",
+			webhookType: "merge_group",
+			payload:     `{"action":"checks_requested","merge_group":{"head_sha":"abc"}}`, // nolint: lll
+			assertions: func(webhook interface{}, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &mergeGroupEvent{}, webhook)
+				// nolint: forcetypeassert
+				w := webhook.(*mergeGroupEvent)
+				require.Equal(t, "checks_requested", w.GetAction())
+				require.Equal(t, "abc", w.GetMergeGroup().GetHeadSHA())
+			},
+		},
+		{
+			name:        "workflow_job webhook",
+			webhookType: "workflow_job",
+			payload:     `{"action":"queued","workflow_job":{"name":"test"}}`,
+			assertions: func(webhook interface{}, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &workflowJobEvent{}, webhook)
+				// nolint: forcetypeassert
+				w := webhook.(*workflowJobEvent)
+				require.Equal(t, "queued", w.GetAction())
+				require.Equal(t, "test", w.GetWorkflowJob().GetName())
+			},
+		},
+		{
+			name:        "workflow_run webhook",
+			webhookType: "workflow_run",
+			payload:     `{"action":"completed","workflow_run":{"name":"build"}}`,
+			assertions: func(webhook interface{}, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &workflowRunEvent{}, webhook)
+				// nolint: forcetypeassert
+				w := webhook.(*workflowRunEvent)
+				require.Equal(t, "completed", w.GetAction())
+				require.Equal(t, "build", w.GetWorkflowRun().GetName())
+			},
+		},
+		{
+			name:        "invalid payload",
+			webhookType: "workflow_run",
+			payload:     "{",
+			assertions: func(_ interface{}, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				parseWebHook(testCase.webhookType, []byte(testCase.payload)),
+			)
+		})
+	}
+}